@@ -0,0 +1,25 @@
+# The following example gates a command on the state of the cluster
+
+data "azureakscommand_cluster" "this" {
+  resource_group_name = "rg-default"
+  name                = "cluster-name"
+}
+
+resource "azureakscommand_invoke" "example" {
+  resource_group_name = data.azureakscommand_cluster.this.resource_group_name
+  name                = data.azureakscommand_cluster.this.name
+
+  command = "kubectl cluster-info"
+
+  lifecycle {
+    precondition {
+      condition     = !data.azureakscommand_cluster.this.run_command_disabled
+      error_message = "run command is disabled on the cluster"
+    }
+
+    precondition {
+      condition     = data.azureakscommand_cluster.this.power_state == "Running"
+      error_message = "cluster is not running"
+    }
+  }
+}
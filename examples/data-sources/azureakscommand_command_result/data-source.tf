@@ -0,0 +1,20 @@
+# The following example starts a long-running command without blocking the apply and retrieves its result later
+
+resource "azureakscommand_invoke" "backfill" {
+  resource_group_name = "rg-default"
+  name                = "cluster-name"
+
+  command = "kubectl exec deploy/my-app -- /app/backfill"
+  wait    = false
+}
+
+data "azureakscommand_command_result" "backfill" {
+  resource_group_name = azureakscommand_invoke.backfill.resource_group_name
+  name                = azureakscommand_invoke.backfill.name
+
+  command_id = azureakscommand_invoke.backfill.id
+}
+
+output "backfill_state" {
+  value = data.azureakscommand_command_result.backfill.provisioning_state
+}
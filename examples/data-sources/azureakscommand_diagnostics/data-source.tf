@@ -0,0 +1,15 @@
+# The following example reports which identity the provider uses and whether it may run commands on the cluster
+
+data "azureakscommand_diagnostics" "example" {
+  resource_group_name = "rg-default"
+  name                = "cluster-name"
+}
+
+output "diagnostics" {
+  value = {
+    credential = data.azureakscommand_diagnostics.example.credential
+    object_id  = data.azureakscommand_diagnostics.example.object_id
+    aad_mode   = data.azureakscommand_diagnostics.example.cluster_aad_mode
+    errors     = data.azureakscommand_diagnostics.example.errors
+  }
+}
@@ -0,0 +1,13 @@
+# The following example inspects the containerd configuration on all nodes matching a label selector
+
+data "azureakscommand_node_command" "containerd" {
+  resource_group_name = "rg-default"
+  name                = "cluster-name"
+
+  node_selector = "kubernetes.azure.com/mode=user"
+  command       = "cat /etc/containerd/config.toml"
+}
+
+output "containerd_config" {
+  value = { for node, result in data.azureakscommand_node_command.containerd.results : node => result.output }
+}
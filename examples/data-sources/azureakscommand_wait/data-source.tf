@@ -0,0 +1,30 @@
+# The following example waits until a deployment is available before dependent resources are created
+
+data "azureakscommand_wait" "deployment" {
+  resource_group_name = "rg-default"
+  name                = "cluster-name"
+
+  resource  = "deployment/my-app"
+  namespace = "default"
+  for       = "condition=Available"
+  timeout   = "15m"
+}
+
+# Wait until a CRD is established
+data "azureakscommand_wait" "crd" {
+  resource_group_name = "rg-default"
+  name                = "cluster-name"
+
+  resource = "crd/certificates.cert-manager.io"
+  for      = "condition=Established"
+}
+
+# Wait through kubectl rollout status
+data "azureakscommand_wait" "rollout" {
+  resource_group_name = "rg-default"
+  name                = "cluster-name"
+
+  resource       = "daemonset/my-agent"
+  namespace      = "kube-system"
+  rollout_status = true
+}
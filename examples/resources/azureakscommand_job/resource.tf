@@ -0,0 +1,35 @@
+# The following example runs a database migration as Kubernetes Job inside a AKS cluster
+
+resource "azureakscommand_job" "migration" {
+  resource_group_name = "rg-default"
+  name                = "cluster-name"
+
+  job_name  = "db-migrate-v42"
+  namespace = "my-app"
+  image     = "ghcr.io/example/my-app:v42"
+  command   = ["/app/migrate"]
+  args      = ["--target", "latest"]
+
+  env = {
+    DATABASE_HOST = "db.example.com"
+  }
+
+  service_account_name = "my-app-migrations"
+  timeout              = "2h"
+
+  # Re-run migration, if the image changes.
+  triggers = {
+    image = "ghcr.io/example/my-app:v42"
+  }
+
+  lifecycle {
+    postcondition {
+      condition     = self.status == "Complete"
+      error_message = "migration failed: ${self.output}"
+    }
+  }
+}
+
+output "migration_output" {
+  value = azureakscommand_job.migration.output
+}
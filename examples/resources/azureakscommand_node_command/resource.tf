@@ -0,0 +1,16 @@
+# The following example raises a kernel parameter on all nodes of a node pool
+
+resource "azureakscommand_node_command" "example" {
+  resource_group_name = "rg-default"
+  name                = "cluster-name"
+
+  node_pool = "default"
+  command   = "sysctl -w vm.max_map_count=262144"
+
+  lifecycle {
+    postcondition {
+      condition     = alltrue([for result in values(self.results) : result.exit_code == 0])
+      error_message = "command failed on at least one node"
+    }
+  }
+}
@@ -0,0 +1,53 @@
+package clients
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+)
+
+const (
+	HeaderAuxiliaryAuthorization = "x-ms-authorization-auxiliary"
+)
+
+type AuxiliaryTenantsPolicy struct {
+	Credential azcore.TokenCredential
+	Scope      string
+	TenantIds  []string
+}
+
+func (c AuxiliaryTenantsPolicy) Do(req *policy.Request) (*http.Response, error) {
+	tokens := make([]string, 0, len(c.TenantIds))
+
+	for _, tenantId := range c.TenantIds {
+		token, err := c.Credential.GetToken(req.Raw().Context(), policy.TokenRequestOptions{
+			Scopes:   []string{c.Scope},
+			TenantID: tenantId,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		tokens = append(tokens, "Bearer "+token.Token)
+	}
+
+	req.Raw().Header.Set(HeaderAuxiliaryAuthorization, strings.Join(tokens, ", "))
+
+	return req.Next()
+}
+
+var _ policy.Policy = AuxiliaryTenantsPolicy{}
+
+// WithAuxiliaryTenants returns a policy.Policy that adds the HTTP header
+// `x-ms-authorization-auxiliary` containing a token of each auxiliary tenant,
+// which authorizes requests against resources of other tenants.
+func WithAuxiliaryTenants(credential azcore.TokenCredential, audience string, tenantIds []string) policy.Policy {
+	scope := audience
+	if !strings.HasSuffix(scope, "/.default") {
+		scope += "/.default"
+	}
+
+	return AuxiliaryTenantsPolicy{Credential: credential, Scope: scope, TenantIds: tenantIds}
+}
@@ -0,0 +1,58 @@
+package clients
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// HttpClientOptions describes the transport settings shared by all HTTP clients of the provider.
+type HttpClientOptions struct {
+	// ProxyUrl is the URL of the HTTP proxy. If empty, the proxy is taken from the HTTP_PROXY, HTTPS_PROXY and
+	// NO_PROXY environment variables.
+	ProxyUrl string
+	// CACertificates contains PEM encoded certificates, which are trusted in addition to the system certificates.
+	CACertificates []byte
+	// Timeout limits the time of a single request. Zero means no timeout.
+	Timeout time.Duration
+}
+
+// NewHttpClient returns a http.Client with the given transport settings. It can be passed as
+// azcore.ClientOptions.Transport.
+func NewHttpClient(options HttpClientOptions) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if options.ProxyUrl != "" {
+		proxyUrl, err := url.Parse(options.ProxyUrl)
+		if err != nil {
+			return nil, fmt.Errorf("parsing proxy url: %w", err)
+		}
+
+		transport.Proxy = http.ProxyURL(proxyUrl)
+	}
+
+	if len(options.CACertificates) > 0 {
+		rootCAs, err := x509.SystemCertPool()
+		if err != nil {
+			rootCAs = x509.NewCertPool()
+		}
+
+		if !rootCAs.AppendCertsFromPEM(options.CACertificates) {
+			return nil, errors.New("no valid PEM encoded certificate found in CA certificates")
+		}
+
+		transport.TLSClientConfig = &tls.Config{
+			MinVersion: tls.VersionTLS12,
+			RootCAs:    rootCAs,
+		}
+	}
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   options.Timeout,
+	}, nil
+}
@@ -0,0 +1,191 @@
+package clients
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+const (
+	HeaderRequestId            = "x-ms-request-id"
+	HeaderCorrelationRequestId = "x-ms-correlation-request-id"
+
+	redacted = "REDACTED"
+)
+
+// redactedHeaders are replaced in the logs, since they contain credentials.
+var redactedHeaders = []string{
+	"Authorization",
+	HeaderAuxiliaryAuthorization,
+}
+
+// redactedFields are replaced in logged JSON payloads. The command and its context may contain secrets and the
+// cluster token grants access to the cluster.
+var redactedFields = map[string]bool{
+	"clustertoken":  true,
+	"command":       true,
+	"context":       true,
+	"access_token":  true,
+	"refresh_token": true,
+}
+
+type LoggingPolicy struct {
+	Enabled bool
+}
+
+func (c LoggingPolicy) Do(req *policy.Request) (*http.Response, error) {
+	if !c.Enabled {
+		return req.Next()
+	}
+
+	ctx := req.Raw().Context()
+
+	fields := map[string]interface{}{
+		"method": req.Raw().Method,
+		"url":    req.Raw().URL.String(),
+	}
+
+	tflog.Debug(ctx, "Sending Azure request", fields)
+
+	if body, err := readRequestBody(req); err == nil && len(body) > 0 {
+		tflog.Trace(ctx, "Azure request body", map[string]interface{}{
+			"url":     req.Raw().URL.String(),
+			"headers": redactHeaders(req.Raw().Header),
+			"body":    redactBody(body),
+		})
+	}
+
+	start := time.Now()
+	resp, err := req.Next()
+
+	fields["duration_ms"] = time.Since(start).Milliseconds()
+
+	if err != nil {
+		fields["error"] = err.Error()
+		tflog.Debug(ctx, "Azure request failed", fields)
+
+		return resp, err
+	}
+
+	fields["status"] = resp.StatusCode
+	fields[HeaderRequestId] = resp.Header.Get(HeaderRequestId)
+	fields[HeaderCorrelationRequestId] = resp.Header.Get(HeaderCorrelationRequestId)
+
+	tflog.Debug(ctx, "Received Azure response", fields)
+
+	if resp.Body != nil {
+		body, readErr := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+
+		if readErr == nil && len(body) > 0 {
+			tflog.Trace(ctx, "Azure response body", map[string]interface{}{
+				"url":  req.Raw().URL.String(),
+				"body": redactBody(body),
+			})
+		}
+	}
+
+	return resp, nil
+}
+
+var _ policy.Policy = LoggingPolicy{}
+
+// WithLogging returns a policy.Policy that logs requests and responses through
+// tflog, if provider logging is enabled by TF_LOG_PROVIDER or TF_LOG.
+// Credentials, the cluster token and the command payloads are redacted.
+func WithLogging() policy.Policy {
+	return LoggingPolicy{Enabled: os.Getenv("TF_LOG_PROVIDER") != "" || os.Getenv("TF_LOG") != ""}
+}
+
+// CorrelationIds returns the request and correlation ID of the failed Azure
+// request, which caused err. An empty string is returned for other errors.
+func CorrelationIds(err error) string {
+	var respErr *azcore.ResponseError
+	if !errors.As(err, &respErr) || respErr.RawResponse == nil {
+		return ""
+	}
+
+	var ids []string
+
+	for _, header := range []string{HeaderRequestId, HeaderCorrelationRequestId} {
+		if v := respErr.RawResponse.Header.Get(header); v != "" {
+			ids = append(ids, fmt.Sprintf("%s: %s", header, v))
+		}
+	}
+
+	return strings.Join(ids, ", ")
+}
+
+func readRequestBody(req *policy.Request) ([]byte, error) {
+	if req.Body() == nil {
+		return nil, nil
+	}
+
+	body, err := io.ReadAll(req.Body())
+	if err != nil {
+		return nil, err
+	}
+
+	return body, req.RewindBody()
+}
+
+func redactHeaders(header http.Header) map[string]string {
+	headers := make(map[string]string, len(header))
+
+	for name := range header {
+		headers[name] = header.Get(name)
+	}
+
+	for _, name := range redactedHeaders {
+		if header.Get(name) != "" {
+			headers[http.CanonicalHeaderKey(name)] = redacted
+		}
+	}
+
+	return headers
+}
+
+// redactBody replaces the values of redactedFields in JSON payloads. Other payloads are redacted entirely.
+func redactBody(body []byte) string {
+	var payload interface{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return redacted
+	}
+
+	out, err := json.Marshal(redactValue(payload))
+	if err != nil {
+		return redacted
+	}
+
+	return string(out)
+}
+
+func redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if redactedFields[strings.ToLower(key)] {
+				v[key] = redacted
+			} else {
+				v[key] = redactValue(item)
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redactValue(item)
+		}
+	}
+
+	return value
+}
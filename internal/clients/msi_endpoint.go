@@ -0,0 +1,37 @@
+package clients
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+)
+
+type MsiEndpointPolicy struct {
+	Endpoint *url.URL
+}
+
+func (c MsiEndpointPolicy) Do(req *policy.Request) (*http.Response, error) {
+	raw := req.Raw()
+
+	raw.URL.Scheme = c.Endpoint.Scheme
+	raw.URL.Host = c.Endpoint.Host
+	raw.URL.Path = c.Endpoint.Path
+	raw.Host = c.Endpoint.Host
+
+	return req.Next()
+}
+
+var _ policy.Policy = MsiEndpointPolicy{}
+
+// WithMsiEndpoint returns a policy.Policy that sends the token requests of a
+// managed identity credential to a custom endpoint instead of the default
+// IMDS endpoint. The query parameters of the request are preserved.
+func WithMsiEndpoint(endpoint string) (policy.Policy, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	return MsiEndpointPolicy{Endpoint: u}, nil
+}
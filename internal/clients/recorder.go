@@ -0,0 +1,303 @@
+package clients
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+)
+
+type RecorderMode int
+
+const (
+	// RecorderModeReplay answers requests from the cassette without any network access.
+	RecorderModeReplay RecorderMode = iota
+	// RecorderModeRecord sends requests to Azure and captures them in the cassette.
+	RecorderModeRecord
+)
+
+const scrubbed = "SCRUBBED"
+
+// FakeSubscriptionId replaces the subscription IDs in cassettes. It's the subscription of the clusters served by fakes
+// and mock responses.
+const FakeSubscriptionId = "00000000-0000-0000-0000-000000000000"
+
+var (
+	scrubSubscriptionRegexp = regexp.MustCompile(`(?i)(/subscriptions/)[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}`)
+	scrubJwtRegexp          = regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`)
+	scrubJsonFieldRegexp    = regexp.MustCompile(`(?i)"(access_token|refresh_token|id_token|clusterToken|client_secret|client_assertion|command|context)"(\s*:\s*)"(?:[^"\\]|\\.)*"`)
+	scrubFormFieldRegexp    = regexp.MustCompile(`(?i)\b(access_token|refresh_token|client_secret|client_assertion|assertion)=[^&]*`)
+
+	scrubbedHeaders = []string{"Authorization", HeaderAuxiliaryAuthorization, "Set-Cookie"}
+)
+
+// Cassette contains the interactions captured by a Recorder.
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+type RecordedRequest struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Body   string `json:"body,omitempty"`
+}
+
+type RecordedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+// Recorder is a policy.Transporter, which records ARM and AAD traffic to a cassette file and replays it. Tokens,
+// cluster tokens, secrets, subscription IDs and the command and context of runCommand requests are scrubbed before
+// they are written, like the payloads logged by LoggingPolicy.
+type Recorder struct {
+	// Replacements are applied to all recorded values, e.g. to replace the names of the recorded resources by the
+	// names used in the tests. They are also applied to requests before they are matched during replay.
+	Replacements map[string]string
+
+	mode      RecorderMode
+	path      string
+	transport policy.Transporter
+
+	mu       sync.Mutex
+	cassette Cassette
+	used     []bool
+}
+
+// NewRecorder returns a Recorder for the cassette file. In record mode, the requests are sent through transport,
+// which defaults to http.DefaultClient. In replay mode, the cassette is loaded.
+func NewRecorder(path string, mode RecorderMode, transport policy.Transporter) (*Recorder, error) {
+	if transport == nil {
+		transport = http.DefaultClient
+	}
+
+	recorder := &Recorder{
+		mode:      mode,
+		path:      path,
+		transport: transport,
+	}
+
+	if mode == RecorderModeRecord {
+		return recorder, nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading cassette: %w", err)
+	}
+
+	if err := json.Unmarshal(content, &recorder.cassette); err != nil {
+		return nil, fmt.Errorf("parsing cassette %q: %w", path, err)
+	}
+
+	recorder.used = make([]bool, len(recorder.cassette.Interactions))
+
+	return recorder, nil
+}
+
+func (r *Recorder) Do(req *http.Request) (*http.Response, error) {
+	if r.mode == RecorderModeRecord {
+		return r.record(req)
+	}
+
+	return r.replay(req)
+}
+
+var _ policy.Transporter = (*Recorder)(nil)
+
+// Save writes the recorded interactions to the cassette file. It does nothing in replay mode.
+func (r *Recorder) Save() error {
+	if r.mode != RecorderModeRecord {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	content, err := json.MarshalIndent(r.cassette, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(r.path, append(content, '\n'), 0o644)
+}
+
+func (r *Recorder) record(req *http.Request) (*http.Response, error) {
+	var requestBody []byte
+
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+
+		requestBody, err = io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+
+		req.Body = io.NopCloser(bytes.NewReader(requestBody))
+	}
+
+	resp, err := r.transport.Do(req)
+	if err != nil {
+		return resp, err
+	}
+
+	responseBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(responseBody))
+
+	header := http.Header{}
+	for name, values := range resp.Header {
+		if isScrubbedHeader(name) {
+			continue
+		}
+
+		for _, value := range values {
+			header.Add(name, r.scrub(value))
+		}
+	}
+
+	r.mu.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, Interaction{
+		Request: RecordedRequest{
+			Method: req.Method,
+			URL:    r.scrub(req.URL.String()),
+			Body:   r.scrub(string(requestBody)),
+		},
+		Response: RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Header:     header,
+			Body:       r.scrub(string(responseBody)),
+		},
+	})
+	r.mu.Unlock()
+
+	return resp, nil
+}
+
+// replay returns the first unused interaction with the method and URL of the request. The api-version is ignored,
+// so cassettes survive updates of the Azure SDK. Interactions with the same request, e.g. polls of a long-running
+// operation, are returned in the recorded order. Once all of them are used, they are replayed again from the start,
+// so a single recording serves repeated reads of a data source. Delays requested through Retry-After headers are
+// shortened, so replays don't wait.
+func (r *Recorder) replay(req *http.Request) (*http.Response, error) {
+	requestUrl := withoutApiVersion(r.scrub(req.URL.String()))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var matching []int
+
+	for i, interaction := range r.cassette.Interactions {
+		if interaction.Request.Method == req.Method && withoutApiVersion(interaction.Request.URL) == requestUrl {
+			matching = append(matching, i)
+		}
+	}
+
+	if len(matching) == 0 {
+		return nil, fmt.Errorf("no recorded interaction in %q for %s %s", r.path, req.Method, requestUrl)
+	}
+
+	next := -1
+
+	for _, i := range matching {
+		if !r.used[i] {
+			next = i
+			break
+		}
+	}
+
+	if next == -1 {
+		for _, i := range matching {
+			r.used[i] = false
+		}
+
+		next = matching[0]
+	}
+
+	r.used[next] = true
+	interaction := r.cassette.Interactions[next]
+
+	header := interaction.Response.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+
+	if header.Get(HeaderRetryAfter) != "" || interaction.Response.StatusCode == http.StatusAccepted {
+		header.Del(HeaderRetryAfter)
+		header.Set(headerRetryAfterMs, "1")
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", interaction.Response.StatusCode, http.StatusText(interaction.Response.StatusCode)),
+		StatusCode:    interaction.Response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(interaction.Response.Body)),
+		ContentLength: int64(len(interaction.Response.Body)),
+		Request:       req,
+	}, nil
+}
+
+// scrub removes secrets and subscription IDs from a recorded value and applies the replacements.
+func (r *Recorder) scrub(value string) string {
+	for old, replacement := range r.Replacements {
+		if old != "" {
+			value = strings.ReplaceAll(value, old, replacement)
+		}
+	}
+
+	value = scrubSubscriptionRegexp.ReplaceAllString(value, "${1}"+FakeSubscriptionId)
+	value = scrubJwtRegexp.ReplaceAllString(value, scrubbed)
+	value = scrubJsonFieldRegexp.ReplaceAllString(value, `"${1}"${2}"`+scrubbed+`"`)
+	value = scrubFormFieldRegexp.ReplaceAllString(value, "${1}="+scrubbed)
+
+	return value
+}
+
+func withoutApiVersion(value string) string {
+	parsed, err := url.Parse(value)
+	if err != nil {
+		return value
+	}
+
+	query := parsed.Query()
+	query.Del("api-version")
+	parsed.RawQuery = query.Encode()
+
+	return parsed.String()
+}
+
+func isScrubbedHeader(name string) bool {
+	for _, header := range scrubbedHeaders {
+		if strings.EqualFold(name, header) {
+			return true
+		}
+	}
+
+	return false
+}
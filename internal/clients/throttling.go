@@ -0,0 +1,52 @@
+package clients
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+const (
+	HeaderRetryAfter = "Retry-After"
+
+	headerRetryAfterMs = "Retry-After-Ms"
+
+	headerRateLimitRemainingPrefix = "X-Ms-Ratelimit-Remaining-"
+)
+
+type ThrottlingPolicy struct{}
+
+func (c ThrottlingPolicy) Do(req *policy.Request) (*http.Response, error) {
+	resp, err := req.Next()
+	if err != nil || resp.StatusCode != http.StatusTooManyRequests {
+		return resp, err
+	}
+
+	fields := map[string]interface{}{
+		"method":                   req.Raw().Method,
+		"url":                      req.Raw().URL.String(),
+		"retry_after":              resp.Header.Get(HeaderRetryAfter),
+		HeaderCorrelationRequestId: resp.Header.Get(HeaderCorrelationRequestId),
+	}
+
+	for name := range resp.Header {
+		if strings.HasPrefix(name, headerRateLimitRemainingPrefix) {
+			fields[strings.ToLower(name)] = resp.Header.Get(name)
+		}
+	}
+
+	tflog.Warn(req.Raw().Context(), "Azure request was throttled", fields)
+
+	return resp, nil
+}
+
+var _ policy.Policy = ThrottlingPolicy{}
+
+// WithThrottlingLogs returns a policy.Policy that logs throttled requests
+// (HTTP 429) including the Retry-After header and the remaining ARM rate
+// limits. The retry itself is done by the retry policy of the pipeline.
+func WithThrottlingLogs() policy.Policy {
+	return ThrottlingPolicy{}
+}
@@ -0,0 +1,112 @@
+package helpers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/cloud"
+)
+
+// cloudMetadataEnvironment describes an entry of the ARM metadata endpoint.
+type cloudMetadataEnvironment struct {
+	Name            string `json:"name"`
+	ResourceManager string `json:"resourceManager"`
+	Authentication  struct {
+		LoginEndpoint string   `json:"loginEndpoint"`
+		Audiences     []string `json:"audiences"`
+	} `json:"authentication"`
+}
+
+// GetCloudConfigurationFromMetadataHost loads the cloud configuration from the ARM metadata endpoint of metadataHost,
+// e.g. management.local.azurestack.external. The endpoint may describe multiple environments, in which case the
+// environment serving the metadata host is preferred, followed by the environment with the given name.
+func GetCloudConfigurationFromMetadataHost(ctx context.Context, client *http.Client, metadataHost string, environment string) (cloud.Configuration, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	metadataURL := url.URL{
+		Scheme:   "https",
+		Host:     metadataHost,
+		Path:     "/metadata/endpoints",
+		RawQuery: "api-version=2022-09-01",
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, metadataURL.String(), nil)
+	if err != nil {
+		return cloud.Configuration{}, err
+	}
+
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return cloud.Configuration{}, fmt.Errorf("requesting cloud metadata from %s: %w", metadataHost, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return cloud.Configuration{}, fmt.Errorf("reading cloud metadata from %s: %w", metadataHost, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return cloud.Configuration{}, fmt.Errorf("requesting cloud metadata from %s: unexpected status code %d: %s", metadataHost, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	// Depending on the api-version and the cloud, the endpoint returns a list of environments or a single one.
+	var environments []cloudMetadataEnvironment
+	if err := json.Unmarshal(body, &environments); err != nil {
+		var single cloudMetadataEnvironment
+		if err := json.Unmarshal(body, &single); err != nil {
+			return cloud.Configuration{}, fmt.Errorf("decoding cloud metadata from %s: %w", metadataHost, err)
+		}
+
+		environments = []cloudMetadataEnvironment{single}
+	}
+
+	selected, err := selectCloudMetadataEnvironment(environments, metadataHost, environment)
+	if err != nil {
+		return cloud.Configuration{}, fmt.Errorf("cloud metadata from %s: %w", metadataHost, err)
+	}
+
+	audience := selected.ResourceManager
+	if len(selected.Authentication.Audiences) > 0 {
+		audience = selected.Authentication.Audiences[0]
+	}
+
+	return cloud.Configuration{
+		ActiveDirectoryAuthorityHost: selected.Authentication.LoginEndpoint,
+		Services: map[cloud.ServiceName]cloud.ServiceConfiguration{
+			cloud.ResourceManager: {
+				Audience: audience,
+				Endpoint: selected.ResourceManager,
+			},
+		},
+	}, nil
+}
+
+func selectCloudMetadataEnvironment(environments []cloudMetadataEnvironment, metadataHost string, environment string) (cloudMetadataEnvironment, error) {
+	for _, e := range environments {
+		if u, err := url.Parse(e.ResourceManager); err == nil && strings.EqualFold(u.Host, metadataHost) {
+			return e, nil
+		}
+	}
+
+	for _, e := range environments {
+		if environment != "" && strings.EqualFold(e.Name, environment) {
+			return e, nil
+		}
+	}
+
+	if len(environments) == 1 {
+		return environments[0], nil
+	}
+
+	return cloudMetadataEnvironment{}, fmt.Errorf("no environment matches the metadata host or environment %q", environment)
+}
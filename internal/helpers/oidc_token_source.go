@@ -0,0 +1,168 @@
+package helpers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// OidcAudience is the audience of ID tokens, which are exchanged for Azure access tokens.
+const OidcAudience = "api://AzureADTokenExchange"
+
+// OidcTokenSource provides ID tokens for the OIDC authentication. Token is called whenever a new access token is
+// requested, so implementations should return a fresh ID token on every call.
+type OidcTokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// StaticOidcTokenSource returns a fixed ID token.
+type StaticOidcTokenSource struct {
+	Value string
+}
+
+func (s StaticOidcTokenSource) Token(_ context.Context) (string, error) {
+	return s.Value, nil
+}
+
+// FileOidcTokenSource reads the ID token from a file, which is rotated by an external process, e.g. the AKS
+// workload identity webhook.
+type FileOidcTokenSource struct {
+	Path string
+}
+
+func (s FileOidcTokenSource) Token(_ context.Context) (string, error) {
+	token, err := os.ReadFile(s.Path)
+	if err != nil {
+		return "", fmt.Errorf("reading OIDC token file %q: %w", s.Path, err)
+	}
+
+	return strings.TrimSpace(string(token)), nil
+}
+
+// EnvironmentOidcTokenSource reads the ID token from an environment variable, e.g. TFC_WORKLOAD_IDENTITY_TOKEN,
+// which is set by HCP Terraform.
+type EnvironmentOidcTokenSource struct {
+	Name string
+}
+
+func (s EnvironmentOidcTokenSource) Token(_ context.Context) (string, error) {
+	token := os.Getenv(s.Name)
+	if token == "" {
+		return "", fmt.Errorf("environment variable %s is empty", s.Name)
+	}
+
+	return token, nil
+}
+
+// GithubActionsOidcTokenSource requests the ID token from the OIDC provider of GitHub Actions.
+type GithubActionsOidcTokenSource struct {
+	RequestUrl   string
+	RequestToken string
+	HttpClient   *http.Client
+}
+
+func (s GithubActionsOidcTokenSource) Token(ctx context.Context) (string, error) {
+	requestURL, err := url.Parse(s.RequestUrl)
+	if err != nil {
+		return "", fmt.Errorf("parsing OIDC request url: %w", err)
+	}
+
+	query := requestURL.Query()
+	query.Set("audience", OidcAudience)
+	requestURL.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL.String(), nil)
+	if err != nil {
+		return "", err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+s.RequestToken)
+	req.Header.Set("Accept", "application/json")
+
+	var payload struct {
+		Value string `json:"value"`
+	}
+
+	if err := doOidcTokenRequest(s.HttpClient, req, &payload); err != nil {
+		return "", fmt.Errorf("requesting OIDC token from GitHub Actions: %w", err)
+	}
+
+	if payload.Value == "" {
+		return "", errors.New("requesting OIDC token from GitHub Actions: response does not contain a token")
+	}
+
+	return payload.Value, nil
+}
+
+// AzureDevOpsOidcTokenSource requests the ID token of an Azure DevOps service connection. RequestUrl and
+// RequestToken are usually sourced from SYSTEM_OIDCREQUESTURI and SYSTEM_ACCESSTOKEN.
+type AzureDevOpsOidcTokenSource struct {
+	RequestUrl          string
+	RequestToken        string
+	ServiceConnectionId string
+	HttpClient          *http.Client
+}
+
+func (s AzureDevOpsOidcTokenSource) Token(ctx context.Context) (string, error) {
+	requestURL, err := url.Parse(s.RequestUrl)
+	if err != nil {
+		return "", fmt.Errorf("parsing OIDC request url: %w", err)
+	}
+
+	query := requestURL.Query()
+	query.Set("api-version", "7.1")
+	query.Set("serviceConnectionId", s.ServiceConnectionId)
+	requestURL.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, requestURL.String(), nil)
+	if err != nil {
+		return "", err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+s.RequestToken)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+
+	var payload struct {
+		OidcToken string `json:"oidcToken"`
+	}
+
+	if err := doOidcTokenRequest(s.HttpClient, req, &payload); err != nil {
+		return "", fmt.Errorf("requesting OIDC token from Azure DevOps: %w", err)
+	}
+
+	if payload.OidcToken == "" {
+		return "", errors.New("requesting OIDC token from Azure DevOps: response does not contain a token")
+	}
+
+	return payload.OidcToken, nil
+}
+
+func doOidcTokenRequest(client *http.Client, req *http.Request, payload any) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(payload); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,126 @@
+package helpers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// oidcTokenServer answers token requests with the status code and body, after checking the request with check.
+func oidcTokenServer(t *testing.T, statusCode int, body string, check func(r *http.Request) error) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if check != nil {
+			if err := check(r); err != nil {
+				t.Errorf("unexpected request %s %s: %s", r.Method, r.URL, err)
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(statusCode)
+		_, _ = w.Write([]byte(body))
+	}))
+
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func checkGithubActionsRequest(r *http.Request) error {
+	switch {
+	case r.Method != http.MethodGet:
+		return errors.New("method is not GET")
+	case r.Header.Get("Authorization") != "Bearer request-token":
+		return errors.New("missing request token")
+	case r.URL.Query().Get("audience") != OidcAudience:
+		return errors.New("missing audience")
+	case r.URL.Query().Get("existing") != "1":
+		return errors.New("query of the request url is not preserved")
+	}
+
+	return nil
+}
+
+func checkAzureDevOpsRequest(r *http.Request) error {
+	switch {
+	case r.Method != http.MethodPost:
+		return errors.New("method is not POST")
+	case r.Header.Get("Authorization") != "Bearer request-token":
+		return errors.New("missing request token")
+	case r.URL.Query().Get("serviceConnectionId") != "connection":
+		return errors.New("missing service connection")
+	case r.URL.Query().Get("api-version") == "":
+		return errors.New("missing api-version")
+	}
+
+	return nil
+}
+
+func TestOidcTokenSources(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("file-token\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("TEST_OIDC_TOKEN", "env-token")
+	t.Setenv("TEST_OIDC_TOKEN_EMPTY", "")
+
+	github := func(statusCode int, body string) OidcTokenSource {
+		server := oidcTokenServer(t, statusCode, body, checkGithubActionsRequest)
+		return GithubActionsOidcTokenSource{RequestUrl: server.URL + "/token?existing=1", RequestToken: "request-token", HttpClient: server.Client()}
+	}
+
+	azureDevOps := func(statusCode int, body string) OidcTokenSource {
+		server := oidcTokenServer(t, statusCode, body, checkAzureDevOpsRequest)
+		return AzureDevOpsOidcTokenSource{RequestUrl: server.URL + "/oidctoken", RequestToken: "request-token", ServiceConnectionId: "connection", HttpClient: server.Client()}
+	}
+
+	tests := []struct {
+		name    string
+		source  OidcTokenSource
+		want    string
+		wantErr string
+	}{
+		{name: "static", source: StaticOidcTokenSource{Value: "static-token"}, want: "static-token"},
+		{name: "file", source: FileOidcTokenSource{Path: tokenFile}, want: "file-token"},
+		{name: "missing file", source: FileOidcTokenSource{Path: filepath.Join(t.TempDir(), "missing")}, wantErr: "reading OIDC token file"},
+		{name: "environment", source: EnvironmentOidcTokenSource{Name: "TEST_OIDC_TOKEN"}, want: "env-token"},
+		{name: "empty environment", source: EnvironmentOidcTokenSource{Name: "TEST_OIDC_TOKEN_EMPTY"}, wantErr: "environment variable TEST_OIDC_TOKEN_EMPTY is empty"},
+		{name: "github actions", source: github(http.StatusOK, `{"value":"github-token"}`), want: "github-token"},
+		{name: "github actions without token", source: github(http.StatusOK, `{}`), wantErr: "response does not contain a token"},
+		{name: "github actions unauthorized", source: github(http.StatusUnauthorized, `{"message":"bad credentials"}`), wantErr: `unexpected status code 401: {"message":"bad credentials"}`},
+		{name: "github actions invalid response", source: github(http.StatusOK, `<html>`), wantErr: "decoding response"},
+		{name: "github actions invalid url", source: GithubActionsOidcTokenSource{RequestUrl: "://"}, wantErr: "parsing OIDC request url"},
+		{name: "azure devops", source: azureDevOps(http.StatusOK, `{"oidcToken":"ado-token"}`), want: "ado-token"},
+		{name: "azure devops without token", source: azureDevOps(http.StatusOK, `{"oidcToken":""}`), wantErr: "response does not contain a token"},
+		{name: "azure devops forbidden", source: azureDevOps(http.StatusForbidden, `denied`), wantErr: "requesting OIDC token from Azure DevOps: unexpected status code 403: denied"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.source.Token(context.Background())
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got != tt.want {
+				t.Errorf("Token() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
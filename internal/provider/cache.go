@@ -0,0 +1,136 @@
+package provider
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/containerservice/armcontainerservice/v9"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	// clusterCacheTTL is the duration cluster lookups are reused. It's short, since only the AAD profile of the
+	// cluster is evaluated, which rarely changes during a Terraform run.
+	clusterCacheTTL = 30 * time.Second
+
+	// tokenRefreshMargin is the remaining lifetime of a cached token, below which a new token is acquired.
+	tokenRefreshMargin = 5 * time.Minute
+
+	// cacheFetchTimeout bounds a deduplicated lookup, which doesn't end with the context of the caller starting it.
+	cacheFetchTimeout = 5 * time.Minute
+)
+
+type cachedCluster struct {
+	cluster   armcontainerservice.ManagedCluster
+	expiresAt time.Time
+}
+
+// clusterCache shares cluster lookups and cluster tokens between all resources and data sources of a provider
+// instance. Concurrent lookups of the same cluster or token are deduplicated. A nil cache disables caching.
+type clusterCache struct {
+	mu       sync.Mutex
+	clusters map[string]cachedCluster
+	tokens   map[string]azcore.AccessToken
+	group    singleflight.Group
+}
+
+func newClusterCache() *clusterCache {
+	return &clusterCache{
+		clusters: map[string]cachedCluster{},
+		tokens:   map[string]azcore.AccessToken{},
+	}
+}
+
+// getCluster returns the Managed Cluster from the cache or retrieves it through fetch.
+func (c *clusterCache) getCluster(ctx context.Context, resourceGroup string, resourceName string, fetch func(ctx context.Context) (armcontainerservice.ManagedCluster, error)) (armcontainerservice.ManagedCluster, error) {
+	if c == nil {
+		return fetch(ctx)
+	}
+
+	key := "cluster/" + strings.ToLower(resourceGroup+"/"+resourceName)
+
+	c.mu.Lock()
+	cached, ok := c.clusters[key]
+	c.mu.Unlock()
+
+	if ok && time.Now().Before(cached.expiresAt) {
+		return cached.cluster, nil
+	}
+
+	value, err := c.do(ctx, key, func(ctx context.Context) (interface{}, error) {
+		cluster, err := fetch(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		c.mu.Lock()
+		c.clusters[key] = cachedCluster{cluster: cluster, expiresAt: time.Now().Add(clusterCacheTTL)}
+		c.mu.Unlock()
+
+		return cluster, nil
+	})
+	if err != nil {
+		return armcontainerservice.ManagedCluster{}, err
+	}
+
+	return value.(armcontainerservice.ManagedCluster), nil
+}
+
+// getToken returns the token of the tenant and scope from the cache or acquires it through fetch. Tokens are reused
+// until they are about to expire.
+func (c *clusterCache) getToken(ctx context.Context, tenantID string, scope string, fetch func(ctx context.Context) (azcore.AccessToken, error)) (string, error) {
+	if c == nil {
+		token, err := fetch(ctx)
+		return token.Token, err
+	}
+
+	key := "token/" + tenantID + "/" + scope
+
+	c.mu.Lock()
+	cached, ok := c.tokens[key]
+	c.mu.Unlock()
+
+	if ok && time.Now().Add(tokenRefreshMargin).Before(cached.ExpiresOn) {
+		return cached.Token, nil
+	}
+
+	value, err := c.do(ctx, key, func(ctx context.Context) (interface{}, error) {
+		token, err := fetch(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		c.mu.Lock()
+		c.tokens[key] = token
+		c.mu.Unlock()
+
+		return token.Token, nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return value.(string), nil
+}
+
+// do runs fn once for all concurrent callers of the same key. fn is detached from the cancellation of the caller
+// starting it, so the other callers don't fail, if that caller is cancelled. Each caller stops waiting for the result
+// once its own context is done.
+func (c *clusterCache) do(ctx context.Context, key string, fn func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	result := c.group.DoChan(key, func() (interface{}, error) {
+		fetchCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), cacheFetchTimeout)
+		defer cancel()
+
+		return fn(fetchCtx)
+	})
+
+	select {
+	case res := <-result:
+		return res.Val, res.Err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,64 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/containerservice/armcontainerservice/v9"
+)
+
+func TestClusterCacheCancelledCaller(t *testing.T) {
+	cache := newClusterCache()
+	started, done := make(chan struct{}), make(chan struct{})
+
+	name := "aks"
+	fetch := func(ctx context.Context) (armcontainerservice.ManagedCluster, error) {
+		close(started)
+
+		select {
+		case <-done:
+			return armcontainerservice.ManagedCluster{Name: &name}, nil
+		case <-ctx.Done():
+			return armcontainerservice.ManagedCluster{}, ctx.Err()
+		}
+	}
+
+	firstCtx, cancelFirst := context.WithCancel(context.Background())
+	firstErr := make(chan error, 1)
+
+	go func() {
+		_, err := cache.getCluster(firstCtx, "rg", "aks", fetch)
+		firstErr <- err
+	}()
+
+	<-started
+
+	second := make(chan error, 1)
+
+	go func() {
+		cluster, err := cache.getCluster(context.Background(), "rg", "aks", func(context.Context) (armcontainerservice.ManagedCluster, error) {
+			return armcontainerservice.ManagedCluster{}, errors.New("lookup is not deduplicated")
+		})
+		if err == nil && (cluster.Name == nil || *cluster.Name != name) {
+			err = errors.New("unexpected cluster")
+		}
+
+		second <- err
+	}()
+
+	// Cancelling the caller, which started the lookup, only stops that caller from waiting.
+	time.Sleep(10 * time.Millisecond)
+	cancelFirst()
+
+	if err := <-firstErr; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the cancelled caller to fail, got %v", err)
+	}
+
+	close(done)
+
+	if err := <-second; err != nil {
+		t.Fatal(err)
+	}
+}
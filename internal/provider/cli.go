@@ -0,0 +1,210 @@
+package provider
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tfsdklog"
+)
+
+// RunCli implements the run subcommand of the provider binary, which executes a single command on a cluster like
+// azureakscommand_invoke, but without Terraform. The provider is configured from the ARM_* Environment Variables.
+// The output of the command is written to stdout, the returned exit code is the one of the command.
+func RunCli(ctx context.Context, version string, args []string, stdout io.Writer, stderr io.Writer) int {
+	return runCli(ctx, &AzureAksCommandProvider{version: version}, args, stdout, stderr)
+}
+
+// runCli implements RunCli for the provider, whose Azure clients may be replaced in tests.
+func runCli(ctx context.Context, p *AzureAksCommandProvider, args []string, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+
+	clusterID := flags.String("cluster-id", "", "The resource ID of the AKS cluster.")
+	command := flags.String("command", "", "The command to run inside the cluster.")
+	contextDir := flags.String("context-dir", "", "A directory, whose files are attached to the command.")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if *command == "" {
+		fmt.Fprintln(stderr, "Error: --command is required.")
+		flags.Usage()
+
+		return 2
+	}
+
+	resourceID, code := parseCliClusterID(*clusterID, stderr)
+	if resourceID == nil {
+		flags.Usage()
+
+		return code
+	}
+
+	commandContext := ""
+
+	if *contextDir != "" {
+		var err error
+
+		commandContext, err = buildCommandContextFromDir(*contextDir)
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: reading context directory: %s\n", err)
+			return 2
+		}
+	}
+
+	ctx = newCliLogger(ctx)
+
+	client, code := newCliAksCommandClient(ctx, p, resourceID, stderr)
+	if code != 0 {
+		return code
+	}
+
+	if err := client.commandPolicy.check(*command); err != nil {
+		fmt.Fprintf(stderr, "Error: %s\n\n%s\n", commandPolicyErrorSummary, err)
+		return 1
+	}
+
+	res, err := runCommand(ctx, client, resourceID.ResourceGroupName, resourceID.Name, *command, commandContext)
+	if err != nil {
+		summary, detail := describeAzureError("Error while executing runCommand", err)
+		fmt.Fprintf(stderr, "Error: %s\n\n%s\n", summary, detail)
+		return 1
+	}
+
+	var result CommandResultModel
+
+	processRunCommand(&res.RunCommandResult, &result)
+
+	fmt.Fprint(stdout, result.Output.ValueString())
+
+	for _, d := range commandResultWarnings(result) {
+		fmt.Fprintf(stderr, "Warning: %s\n\n%s\n", d.Summary(), d.Detail())
+	}
+
+	if state := result.ProvisioningState.ValueString(); state != "Succeeded" {
+		fmt.Fprintf(stderr, "Error: runCommand finished with provisioning state %q: %s\n", state, result.ProvisioningReason.ValueString())
+		return 1
+	}
+
+	return int(result.ExitCode.ValueInt64())
+}
+
+// DoctorCli implements the doctor subcommand of the provider binary, which runs the checks of the
+// azureakscommand_diagnostics data source against a cluster and prints the report. It returns 1 if a check failed.
+func DoctorCli(ctx context.Context, version string, args []string, stdout io.Writer, stderr io.Writer) int {
+	return doctorCli(ctx, &AzureAksCommandProvider{version: version}, args, stdout, stderr)
+}
+
+// doctorCli implements DoctorCli for the provider, whose Azure clients may be replaced in tests.
+func doctorCli(ctx context.Context, p *AzureAksCommandProvider, args []string, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+
+	clusterID := flags.String("cluster-id", "", "The resource ID of the AKS cluster.")
+	runTestCommand := flags.Bool("run-test-command", true, "Run the command \"true\" on the cluster to verify the permissions for runCommand.")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	resourceID, code := parseCliClusterID(*clusterID, stderr)
+	if resourceID == nil {
+		flags.Usage()
+
+		return code
+	}
+
+	ctx = newCliLogger(ctx)
+
+	client, code := newCliAksCommandClient(ctx, p, resourceID, stderr)
+	if code != 0 {
+		return code
+	}
+
+	report := runDiagnostics(ctx, client, resourceID.ResourceGroupName, resourceID.Name, *runTestCommand)
+
+	fmt.Fprint(stdout, report.String())
+
+	if len(report.Errors) > 0 {
+		return 1
+	}
+
+	return 0
+}
+
+// parseCliClusterID parses the --cluster-id flag. On error, nil and the exit code are returned.
+func parseCliClusterID(clusterID string, stderr io.Writer) (*arm.ResourceID, int) {
+	if clusterID == "" {
+		fmt.Fprintln(stderr, "Error: --cluster-id is required.")
+		return nil, 2
+	}
+
+	resourceID, err := arm.ParseResourceID(clusterID)
+	if err != nil || !strings.EqualFold(resourceID.ResourceType.String(), "Microsoft.ContainerService/managedClusters") {
+		fmt.Fprintf(stderr, "Error: %q is not the resource ID of a Managed Cluster.\n", clusterID)
+		return nil, 2
+	}
+
+	return resourceID, 0
+}
+
+// newCliAksCommandClient configures the provider from the Environment Variables for the subscription of the cluster.
+func newCliAksCommandClient(ctx context.Context, p *AzureAksCommandProvider, resourceID *arm.ResourceID, stderr io.Writer) (AzureAksCommandClient, int) {
+	client, diags := p.newAksCommandClient(ctx, AzureAksCommandProviderModel{
+		SubscriptionId: types.StringValue(resourceID.SubscriptionID),
+	}, "")
+	if diags.HasError() {
+		for _, d := range diags.Errors() {
+			fmt.Fprintf(stderr, "Error: %s: %s\n", d.Summary(), d.Detail())
+		}
+
+		return client, 1
+	}
+
+	return client, 0
+}
+
+// newCliLogger enables the logs of the provider on stderr, if TF_LOG_PROVIDER or TF_LOG is set like for Terraform.
+func newCliLogger(ctx context.Context) context.Context {
+	for _, env := range []string{"TF_LOG_PROVIDER", "TF_LOG"} {
+		if os.Getenv(env) != "" {
+			return tfsdklog.NewRootProviderLogger(ctx, tfsdklog.WithLevelFromEnv(env), tfsdklog.WithLogName("azureakscommand"))
+		}
+	}
+
+	return ctx
+}
+
+// buildCommandContextFromDir returns the context of runCommand containing all files below dir.
+func buildCommandContextFromDir(dir string) (string, error) {
+	files := map[string][]byte{}
+
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+
+		name, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		files[filepath.ToSlash(name)], err = os.ReadFile(path)
+
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return buildCommandContext(files)
+}
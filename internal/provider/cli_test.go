@@ -0,0 +1,213 @@
+package provider
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	azfake "github.com/Azure/azure-sdk-for-go/sdk/azcore/fake"
+
+	"github.com/jkroepke/terraform-provider-azureakscommand/internal/clients"
+)
+
+const testCliClusterId = "/subscriptions/" + clients.FakeSubscriptionId + "/resourceGroups/rg/providers/Microsoft.ContainerService/managedClusters/plain"
+
+// newCliTestProvider returns a provider, which runs the commands of the CLI against newFakeBackend.
+func newCliTestProvider(t *testing.T) *AzureAksCommandProvider {
+	t.Helper()
+
+	managedClustersClient, err := newFakeBackend().NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("ARM_AKSCOMMAND_ALLOWED_COMMAND_PATTERNS", "")
+	t.Setenv("ARM_AKSCOMMAND_DENIED_COMMAND_PATTERNS", "")
+	t.Setenv("ARM_AKSCOMMAND_MOCK_FILE", "")
+
+	return &AzureAksCommandProvider{
+		version:               "test",
+		tokenCredential:       &azfake.TokenCredential{},
+		managedClustersClient: managedClustersClient,
+	}
+}
+
+func TestRunCli(t *testing.T) {
+	contextDir := t.TempDir()
+
+	tests := []struct {
+		name       string
+		args       []string
+		env        map[string]string
+		wantCode   int
+		wantStdout string
+		wantStderr string
+	}{
+		{
+			name:       "output",
+			args:       []string{"--cluster-id", testCliClusterId, "--command", "echo hello"},
+			wantStdout: "hello\n",
+		},
+		{
+			name:       "exit code of the command",
+			args:       []string{"--cluster-id", testCliClusterId, "--command", "exit 3"},
+			wantCode:   3,
+			wantStdout: "failed\n",
+		},
+		{
+			name:       "context directory",
+			args:       []string{"--cluster-id", testCliClusterId, "--command", "kubectl apply -f .", "--context-dir", contextDir},
+			wantStdout: "hello\n",
+		},
+		{
+			name:       "failed command",
+			args:       []string{"--cluster-id", testCliClusterId, "--command", "broken"},
+			wantCode:   1,
+			wantStderr: "pod failed to start",
+		},
+		{
+			name:       "rejected request",
+			args:       []string{"--cluster-id", testCliClusterId, "--command", "busy"},
+			wantCode:   1,
+			wantStderr: "Conflict",
+		},
+		{
+			name:       "missing cluster",
+			args:       []string{"--cluster-id", strings.Replace(testCliClusterId, "plain", "missing", 1), "--command", "echo hello"},
+			wantCode:   1,
+			wantStderr: "Managed Cluster not found",
+		},
+		{
+			name:       "command policy",
+			args:       []string{"--cluster-id", testCliClusterId, "--command", "rm -rf /data"},
+			env:        map[string]string{"ARM_AKSCOMMAND_DENIED_COMMAND_PATTERNS": "rm\\s+-rf"},
+			wantCode:   1,
+			wantStderr: commandPolicyErrorSummary,
+		},
+		{
+			name:       "missing command",
+			args:       []string{"--cluster-id", testCliClusterId},
+			wantCode:   2,
+			wantStderr: "--command is required",
+		},
+		{
+			name:       "missing cluster id",
+			args:       []string{"--command", "echo hello"},
+			wantCode:   2,
+			wantStderr: "--cluster-id is required",
+		},
+		{
+			name:       "invalid cluster id",
+			args:       []string{"--cluster-id", "/subscriptions/" + clients.FakeSubscriptionId + "/resourceGroups/rg", "--command", "echo hello"},
+			wantCode:   2,
+			wantStderr: "is not the resource ID of a Managed Cluster",
+		},
+		{
+			name:       "missing context directory",
+			args:       []string{"--cluster-id", testCliClusterId, "--command", "echo hello", "--context-dir", filepath.Join(contextDir, "missing")},
+			wantCode:   2,
+			wantStderr: "reading context directory",
+		},
+		{
+			name:       "unknown flag",
+			args:       []string{"--cluster", testCliClusterId},
+			wantCode:   2,
+			wantStderr: "flag provided but not defined",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newCliTestProvider(t)
+
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+
+			var stdout, stderr bytes.Buffer
+
+			code := runCli(context.Background(), p, tt.args, &stdout, &stderr)
+
+			if code != tt.wantCode {
+				t.Errorf("exit code %d, want %d, stderr: %s", code, tt.wantCode, stderr.String())
+			}
+
+			if stdout.String() != tt.wantStdout {
+				t.Errorf("stdout %q, want %q", stdout.String(), tt.wantStdout)
+			}
+
+			if tt.wantStderr != "" && !strings.Contains(stderr.String(), tt.wantStderr) {
+				t.Errorf("stderr %q does not contain %q", stderr.String(), tt.wantStderr)
+			}
+		})
+	}
+}
+
+func TestBuildCommandContextFromDir(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"kustomization.yaml":        "resources: [manifests]\n",
+		"manifests/deployment.yaml": "kind: Deployment\n",
+		"manifests/nested/job.yaml": "kind: Job\n",
+	}
+
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	commandContext, err := buildCommandContextFromDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	archive, err := base64.StdEncoding.DecodeString(commandContext)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(reader.File) != len(files) {
+		t.Fatalf("archive contains %d files, want %d", len(reader.File), len(files))
+	}
+
+	for _, file := range reader.File {
+		f, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		content, err := io.ReadAll(f)
+		f.Close()
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if want, ok := files[file.Name]; !ok || string(content) != want {
+			t.Errorf("unexpected file %q with content %q", file.Name, content)
+		}
+	}
+
+	if _, err := buildCommandContextFromDir(filepath.Join(dir, "missing")); err == nil {
+		t.Error("expected an error for a missing directory")
+	}
+}
@@ -0,0 +1,447 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/containerservice/armcontainerservice/v9"
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ datasource.DataSource = &ClusterDataSource{}
+
+func NewClusterDataSource() datasource.DataSource {
+	return &ClusterDataSource{}
+}
+
+// ClusterDataSource defines the data source implementation.
+type ClusterDataSource struct {
+	data AzureAksCommandClient
+}
+
+// ClusterModel describes the data source data model.
+type ClusterModel struct {
+	Id                              types.String `tfsdk:"id"`
+	Name                            types.String `tfsdk:"name"`
+	ResourceGroupName               types.String `tfsdk:"resource_group_name"`
+	Location                        types.String `tfsdk:"location"`
+	KubernetesVersion               types.String `tfsdk:"kubernetes_version"`
+	CurrentKubernetesVersion        types.String `tfsdk:"current_kubernetes_version"`
+	PowerState                      types.String `tfsdk:"power_state"`
+	ProvisioningState               types.String `tfsdk:"provisioning_state"`
+	Fqdn                            types.String `tfsdk:"fqdn"`
+	PrivateFqdn                     types.String `tfsdk:"private_fqdn"`
+	AadManaged                      types.Bool   `tfsdk:"aad_managed"`
+	AadTenantId                     types.String `tfsdk:"aad_tenant_id"`
+	AzureRbacEnabled                types.Bool   `tfsdk:"azure_rbac_enabled"`
+	RbacEnabled                     types.Bool   `tfsdk:"rbac_enabled"`
+	LocalAccountsDisabled           types.Bool   `tfsdk:"local_accounts_disabled"`
+	RunCommandDisabled              types.Bool   `tfsdk:"run_command_disabled"`
+	PrivateClusterEnabled           types.Bool   `tfsdk:"private_cluster_enabled"`
+	PrivateClusterPublicFqdnEnabled types.Bool   `tfsdk:"private_cluster_public_fqdn_enabled"`
+	NodePools                       types.List   `tfsdk:"node_pools"`
+	Identity                        types.Object `tfsdk:"identity"`
+	KubeletIdentity                 types.Object `tfsdk:"kubelet_identity"`
+}
+
+// ClusterNodePoolModel describes a node pool of the cluster.
+type ClusterNodePoolModel struct {
+	Name                types.String `tfsdk:"name"`
+	Mode                types.String `tfsdk:"mode"`
+	VmSize              types.String `tfsdk:"vm_size"`
+	Count               types.Int64  `tfsdk:"count"`
+	OsType              types.String `tfsdk:"os_type"`
+	OrchestratorVersion types.String `tfsdk:"orchestrator_version"`
+	PowerState          types.String `tfsdk:"power_state"`
+	ProvisioningState   types.String `tfsdk:"provisioning_state"`
+}
+
+// ClusterIdentityModel describes the managed identity of the cluster.
+type ClusterIdentityModel struct {
+	Type                    types.String `tfsdk:"type"`
+	PrincipalId             types.String `tfsdk:"principal_id"`
+	TenantId                types.String `tfsdk:"tenant_id"`
+	UserAssignedIdentityIds types.List   `tfsdk:"user_assigned_identity_ids"`
+}
+
+// ClusterKubeletIdentityModel describes the identity used by the kubelet.
+type ClusterKubeletIdentityModel struct {
+	ClientId   types.String `tfsdk:"client_id"`
+	ObjectId   types.String `tfsdk:"object_id"`
+	ResourceId types.String `tfsdk:"resource_id"`
+}
+
+var clusterNodePoolType = types.ObjectType{
+	AttrTypes: map[string]attr.Type{
+		"name":                 types.StringType,
+		"mode":                 types.StringType,
+		"vm_size":              types.StringType,
+		"count":                types.Int64Type,
+		"os_type":              types.StringType,
+		"orchestrator_version": types.StringType,
+		"power_state":          types.StringType,
+		"provisioning_state":   types.StringType,
+	},
+}
+
+var clusterIdentityAttrTypes = map[string]attr.Type{
+	"type":                       types.StringType,
+	"principal_id":               types.StringType,
+	"tenant_id":                  types.StringType,
+	"user_assigned_identity_ids": types.ListType{ElemType: types.StringType},
+}
+
+var clusterKubeletIdentityAttrTypes = map[string]attr.Type{
+	"client_id":   types.StringType,
+	"object_id":   types.StringType,
+	"resource_id": types.StringType,
+}
+
+func (d *ClusterDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_cluster"
+}
+
+func (d *ClusterDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "A data source to retrieve information about a AKS, which are relevant for runCommand executions. " +
+			"This can be used to gate `azureakscommand_invoke` resources with preconditions, e.g. if run command is disabled or the cluster is stopped.",
+		Attributes: map[string]schema.Attribute{
+			"name": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "The name of the Managed Kubernetes Cluster.",
+			},
+			"resource_group_name": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "Specifies the Resource Group where the Managed Kubernetes Cluster exists.",
+			},
+			"id": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "The ID of the Managed Kubernetes Cluster",
+			},
+			"location": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "The Azure Region where the Managed Kubernetes Cluster exists.",
+			},
+			"kubernetes_version": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "The configured Kubernetes version.",
+			},
+			"current_kubernetes_version": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "The Kubernetes version the cluster is running.",
+			},
+			"power_state": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "The power state of the cluster. Either `Running` or `Stopped`.",
+			},
+			"provisioning_state": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "The provisioning state of the cluster.",
+			},
+			"fqdn": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "The FQDN of the Kubernetes API server.",
+			},
+			"private_fqdn": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "The FQDN of the Kubernetes API server for private clusters.",
+			},
+			"aad_managed": schema.BoolAttribute{
+				Computed:            true,
+				MarkdownDescription: "Whether the AKS-managed Azure AD integration is enabled. If enabled, runCommand requires an AAD token for the cluster.",
+			},
+			"aad_tenant_id": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "The Tenant ID used by the Azure AD integration.",
+			},
+			"azure_rbac_enabled": schema.BoolAttribute{
+				Computed:            true,
+				MarkdownDescription: "Whether Azure RBAC for Kubernetes authorization is enabled.",
+			},
+			"rbac_enabled": schema.BoolAttribute{
+				Computed:            true,
+				MarkdownDescription: "Whether Kubernetes RBAC is enabled.",
+			},
+			"local_accounts_disabled": schema.BoolAttribute{
+				Computed:            true,
+				MarkdownDescription: "Whether local accounts are disabled.",
+			},
+			"run_command_disabled": schema.BoolAttribute{
+				Computed:            true,
+				MarkdownDescription: "Whether run command is disabled on the cluster.",
+			},
+			"private_cluster_enabled": schema.BoolAttribute{
+				Computed:            true,
+				MarkdownDescription: "Whether the cluster is a private cluster.",
+			},
+			"private_cluster_public_fqdn_enabled": schema.BoolAttribute{
+				Computed:            true,
+				MarkdownDescription: "Whether a public FQDN is created for the private cluster.",
+			},
+			"node_pools": schema.ListNestedAttribute{
+				Computed:            true,
+				MarkdownDescription: "The node pools of the cluster.",
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"name": schema.StringAttribute{
+							Computed:            true,
+							MarkdownDescription: "The name of the node pool.",
+						},
+						"mode": schema.StringAttribute{
+							Computed:            true,
+							MarkdownDescription: "The mode of the node pool. Either `System` or `User`.",
+						},
+						"vm_size": schema.StringAttribute{
+							Computed:            true,
+							MarkdownDescription: "The size of the virtual machines.",
+						},
+						"count": schema.Int64Attribute{
+							Computed:            true,
+							MarkdownDescription: "The number of nodes.",
+						},
+						"os_type": schema.StringAttribute{
+							Computed:            true,
+							MarkdownDescription: "The operating system type of the nodes.",
+						},
+						"orchestrator_version": schema.StringAttribute{
+							Computed:            true,
+							MarkdownDescription: "The Kubernetes version the node pool is running.",
+						},
+						"power_state": schema.StringAttribute{
+							Computed:            true,
+							MarkdownDescription: "The power state of the node pool.",
+						},
+						"provisioning_state": schema.StringAttribute{
+							Computed:            true,
+							MarkdownDescription: "The provisioning state of the node pool.",
+						},
+					},
+				},
+			},
+			"identity": schema.SingleNestedAttribute{
+				Computed:            true,
+				MarkdownDescription: "The managed identity of the cluster.",
+				Attributes: map[string]schema.Attribute{
+					"type": schema.StringAttribute{
+						Computed:            true,
+						MarkdownDescription: "The type of the managed identity.",
+					},
+					"principal_id": schema.StringAttribute{
+						Computed:            true,
+						MarkdownDescription: "The Principal ID of the system assigned identity.",
+					},
+					"tenant_id": schema.StringAttribute{
+						Computed:            true,
+						MarkdownDescription: "The Tenant ID of the system assigned identity.",
+					},
+					"user_assigned_identity_ids": schema.ListAttribute{
+						Computed:            true,
+						ElementType:         types.StringType,
+						MarkdownDescription: "The IDs of the user assigned identities.",
+					},
+				},
+			},
+			"kubelet_identity": schema.SingleNestedAttribute{
+				Computed:            true,
+				MarkdownDescription: "The identity used by the kubelet.",
+				Attributes: map[string]schema.Attribute{
+					"client_id": schema.StringAttribute{
+						Computed:            true,
+						MarkdownDescription: "The Client ID of the kubelet identity.",
+					},
+					"object_id": schema.StringAttribute{
+						Computed:            true,
+						MarkdownDescription: "The Object ID of the kubelet identity.",
+					},
+					"resource_id": schema.StringAttribute{
+						Computed:            true,
+						MarkdownDescription: "The Resource ID of the kubelet identity.",
+					},
+				},
+			},
+		},
+	}
+}
+
+func (d *ClusterDataSource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(AzureAksCommandClient)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected AzureAksCommandClient, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	d.data = data
+}
+
+func (d *ClusterDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data *ClusterModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	// Prevent panic if the provider has not been configured.
+	if d.data.managedClustersClient == nil {
+		resp.Diagnostics.AddError(
+			"Unconfigured Client",
+			"Expected configured client. Please report this issue to the provider developers.",
+		)
+	}
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	res, err := d.data.managedClustersClient.Get(ctx, data.ResourceGroupName.ValueString(), data.Name.ValueString(), nil)
+	if err != nil {
+		addAzureError(&resp.Diagnostics, "Error while retrieving Managed Cluster",
+			fmt.Errorf("retrieving Managed Cluster %q (Resource Group %q): %w", data.Name.ValueString(), data.ResourceGroupName.ValueString(), err))
+
+		return
+	}
+
+	resp.Diagnostics.Append(processManagedCluster(ctx, res.ManagedCluster, data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func processManagedCluster(ctx context.Context, cluster armcontainerservice.ManagedCluster, data *ClusterModel) diag.Diagnostics {
+	var diags, d diag.Diagnostics
+
+	properties := cluster.Properties
+	if properties == nil {
+		properties = &armcontainerservice.ManagedClusterProperties{}
+	}
+
+	data.Id = types.StringPointerValue(cluster.ID)
+	data.Location = types.StringPointerValue(cluster.Location)
+	data.KubernetesVersion = types.StringPointerValue(properties.KubernetesVersion)
+	data.CurrentKubernetesVersion = types.StringPointerValue(properties.CurrentKubernetesVersion)
+	data.PowerState = powerStateValue(properties.PowerState)
+	data.ProvisioningState = types.StringPointerValue(properties.ProvisioningState)
+	data.Fqdn = types.StringPointerValue(properties.Fqdn)
+	data.PrivateFqdn = types.StringPointerValue(properties.PrivateFQDN)
+	data.RbacEnabled = types.BoolPointerValue(properties.EnableRBAC)
+	data.LocalAccountsDisabled = types.BoolValue(properties.DisableLocalAccounts != nil && *properties.DisableLocalAccounts)
+	data.AadManaged = types.BoolValue(isAADManagedCluster(cluster))
+	data.AadTenantId = types.StringNull()
+	data.AzureRbacEnabled = types.BoolValue(false)
+	data.RunCommandDisabled = types.BoolValue(false)
+	data.PrivateClusterEnabled = types.BoolValue(false)
+	data.PrivateClusterPublicFqdnEnabled = types.BoolValue(false)
+
+	if aadProfile := properties.AADProfile; aadProfile != nil {
+		data.AadTenantId = types.StringPointerValue(aadProfile.TenantID)
+		data.AzureRbacEnabled = types.BoolValue(aadProfile.EnableAzureRBAC != nil && *aadProfile.EnableAzureRBAC)
+	}
+
+	if accessProfile := properties.APIServerAccessProfile; accessProfile != nil {
+		data.RunCommandDisabled = types.BoolValue(accessProfile.DisableRunCommand != nil && *accessProfile.DisableRunCommand)
+		data.PrivateClusterEnabled = types.BoolValue(accessProfile.EnablePrivateCluster != nil && *accessProfile.EnablePrivateCluster)
+		data.PrivateClusterPublicFqdnEnabled = types.BoolValue(accessProfile.EnablePrivateClusterPublicFQDN != nil && *accessProfile.EnablePrivateClusterPublicFQDN)
+	}
+
+	nodePools := make([]ClusterNodePoolModel, 0, len(properties.AgentPoolProfiles))
+
+	for _, profile := range properties.AgentPoolProfiles {
+		if profile == nil {
+			continue
+		}
+
+		nodePool := ClusterNodePoolModel{
+			Name:                types.StringPointerValue(profile.Name),
+			Mode:                types.StringNull(),
+			VmSize:              types.StringPointerValue(profile.VMSize),
+			Count:               types.Int64Null(),
+			OsType:              types.StringNull(),
+			OrchestratorVersion: types.StringPointerValue(profile.CurrentOrchestratorVersion),
+			PowerState:          powerStateValue(profile.PowerState),
+			ProvisioningState:   types.StringPointerValue(profile.ProvisioningState),
+		}
+
+		if profile.Mode != nil {
+			nodePool.Mode = types.StringValue(string(*profile.Mode))
+		}
+
+		if profile.Count != nil {
+			nodePool.Count = types.Int64Value(int64(*profile.Count))
+		}
+
+		if profile.OSType != nil {
+			nodePool.OsType = types.StringValue(string(*profile.OSType))
+		}
+
+		nodePools = append(nodePools, nodePool)
+	}
+
+	data.NodePools, d = types.ListValueFrom(ctx, clusterNodePoolType, nodePools)
+	diags.Append(d...)
+
+	data.Identity = types.ObjectNull(clusterIdentityAttrTypes)
+
+	if cluster.Identity != nil {
+		userAssignedIdentityIds := make([]string, 0, len(cluster.Identity.UserAssignedIdentities))
+		for id := range cluster.Identity.UserAssignedIdentities {
+			userAssignedIdentityIds = append(userAssignedIdentityIds, id)
+		}
+
+		sort.Strings(userAssignedIdentityIds)
+
+		identity := ClusterIdentityModel{
+			Type:        types.StringNull(),
+			PrincipalId: types.StringPointerValue(cluster.Identity.PrincipalID),
+			TenantId:    types.StringPointerValue(cluster.Identity.TenantID),
+		}
+
+		if cluster.Identity.Type != nil {
+			identity.Type = types.StringValue(string(*cluster.Identity.Type))
+		}
+
+		identity.UserAssignedIdentityIds, d = types.ListValueFrom(ctx, types.StringType, userAssignedIdentityIds)
+		diags.Append(d...)
+
+		data.Identity, d = types.ObjectValueFrom(ctx, clusterIdentityAttrTypes, identity)
+		diags.Append(d...)
+	}
+
+	data.KubeletIdentity = types.ObjectNull(clusterKubeletIdentityAttrTypes)
+
+	if kubeletIdentity, ok := properties.IdentityProfile["kubeletidentity"]; ok && kubeletIdentity != nil {
+		data.KubeletIdentity, d = types.ObjectValueFrom(ctx, clusterKubeletIdentityAttrTypes, ClusterKubeletIdentityModel{
+			ClientId:   types.StringPointerValue(kubeletIdentity.ClientID),
+			ObjectId:   types.StringPointerValue(kubeletIdentity.ObjectID),
+			ResourceId: types.StringPointerValue(kubeletIdentity.ResourceID),
+		})
+		diags.Append(d...)
+	}
+
+	return diags
+}
+
+func powerStateValue(powerState *armcontainerservice.PowerState) types.String {
+	if powerState == nil || powerState.Code == nil {
+		return types.StringNull()
+	}
+
+	return types.StringValue(string(*powerState.Code))
+}
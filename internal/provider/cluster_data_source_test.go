@@ -0,0 +1,53 @@
+package provider
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func TestAccClusterDataSource(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactoriesWithFake(t, newFakeBackend()),
+		Steps: []resource.TestStep{
+			{
+				Config: `
+data "azureakscommand_cluster" "plain" {
+  resource_group_name = "rg"
+  name                = "plain"
+}
+
+data "azureakscommand_cluster" "aad" {
+  resource_group_name = "rg"
+  name                = "aad"
+}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.azureakscommand_cluster.plain", "location", "westeurope"),
+					resource.TestCheckResourceAttr("data.azureakscommand_cluster.plain", "aad_managed", "false"),
+					resource.TestCheckNoResourceAttr("data.azureakscommand_cluster.plain", "aad_tenant_id"),
+					resource.TestCheckResourceAttr("data.azureakscommand_cluster.aad", "aad_managed", "true"),
+					resource.TestCheckResourceAttr("data.azureakscommand_cluster.aad", "aad_tenant_id", "11111111-1111-1111-1111-111111111111"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccClusterDataSourceNotFound(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactoriesWithFake(t, newFakeBackend()),
+		Steps: []resource.TestStep{
+			{
+				Config: `
+data "azureakscommand_cluster" "test" {
+  resource_group_name = "rg"
+  name                = "missing"
+}
+`,
+				ExpectError: regexp.MustCompile("Managed Cluster not found"),
+			},
+		},
+	})
+}
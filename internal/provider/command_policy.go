@@ -0,0 +1,99 @@
+package provider
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+)
+
+// commandPolicyErrorSummary is the summary of diagnostics for commands rejected by the provider policy.
+const commandPolicyErrorSummary = "Command rejected by provider policy"
+
+// commandPolicy restricts the commands sent to runCommand. It's configured through the allowed_command_patterns and
+// denied_command_patterns attributes of the provider. azureakscommand_job and azureakscommand_node_command check the
+// command of the user instead of the script or kubectl commands running it, see jobCommandLine and runNodeCommand.
+type commandPolicy struct {
+	allowed []*regexp.Regexp
+	denied  []*regexp.Regexp
+}
+
+// commandPolicyError is returned for commands, which are rejected by the commandPolicy.
+type commandPolicyError struct {
+	command string
+	reason  string
+}
+
+func (e *commandPolicyError) Error() string {
+	return fmt.Sprintf("command %q is rejected by the provider policy: %s", e.command, e.reason)
+}
+
+// newCommandPolicy compiles the patterns of the policy. If both lists are empty, nil is returned, which allows every
+// command.
+func newCommandPolicy(allowedPatterns []string, deniedPatterns []string) (*commandPolicy, error) {
+	if len(allowedPatterns) == 0 && len(deniedPatterns) == 0 {
+		return nil, nil
+	}
+
+	allowed, err := compileCommandPatterns(allowedPatterns)
+	if err != nil {
+		return nil, fmt.Errorf("allowed_command_patterns: %w", err)
+	}
+
+	denied, err := compileCommandPatterns(deniedPatterns)
+	if err != nil {
+		return nil, fmt.Errorf("denied_command_patterns: %w", err)
+	}
+
+	return &commandPolicy{allowed: allowed, denied: denied}, nil
+}
+
+func compileCommandPatterns(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+
+		compiled = append(compiled, re)
+	}
+
+	return compiled, nil
+}
+
+// check returns a *commandPolicyError, if the command matches one of the denied patterns or, if allowed patterns are
+// configured, none of them. Denied patterns take precedence over allowed patterns.
+func (p *commandPolicy) check(command string) error {
+	if p == nil {
+		return nil
+	}
+
+	for _, re := range p.denied {
+		if re.MatchString(command) {
+			return &commandPolicyError{command: command, reason: fmt.Sprintf("it matches the denied_command_patterns entry %q", re.String())}
+		}
+	}
+
+	if len(p.allowed) == 0 {
+		return nil
+	}
+
+	for _, re := range p.allowed {
+		if re.MatchString(command) {
+			return nil
+		}
+	}
+
+	return &commandPolicyError{command: command, reason: "it matches none of the allowed_command_patterns"}
+}
+
+// addCommandPolicyError adds an attribute error, if the command is rejected by the policy of the client. It's used at
+// plan time and again before the command is executed, since the command may be unknown at plan time.
+func addCommandPolicyError(diags *diag.Diagnostics, attributePath path.Path, client AzureAksCommandClient, command string) {
+	if err := client.commandPolicy.check(command); err != nil {
+		diags.AddAttributeError(attributePath, commandPolicyErrorSummary, err.Error())
+	}
+}
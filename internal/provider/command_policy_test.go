@@ -0,0 +1,190 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func TestCommandPolicy(t *testing.T) {
+	policy, err := newCommandPolicy(
+		[]string{`^kubectl\s`, `^helm\s`},
+		[]string{`kubectl\s+delete\s+(ns|namespace)\b`, `^helm\s+uninstall\b`},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		command string
+		allowed bool
+	}{
+		{command: "kubectl get pods -A", allowed: true},
+		{command: "helm list -A", allowed: true},
+		{command: "kubectl delete namespace team-a", allowed: false},
+		{command: "kubectl delete ns team-a", allowed: false},
+		{command: "helm uninstall ingress-nginx", allowed: false},
+		{command: "rm -rf /", allowed: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			if err := policy.check(tt.command); (err == nil) != tt.allowed {
+				t.Errorf("check(%q) = %v, want allowed %t", tt.command, err, tt.allowed)
+			}
+		})
+	}
+
+	if _, err := newCommandPolicy(nil, []string{"("}); err == nil {
+		t.Error("expected an error for an invalid pattern")
+	}
+
+	policy, err = newCommandPolicy(nil, nil)
+	if err != nil || policy.check("anything") != nil {
+		t.Errorf("expected an empty policy to allow every command, got %v", err)
+	}
+}
+
+func TestCommandPolicyBeforeInvokeCommand(t *testing.T) {
+	backend := &FakeManagedClusters{}
+
+	client := newFakeClient(t, backend)
+	client.commandPolicy, _ = newCommandPolicy(nil, []string{`^helm\s+uninstall\b`})
+
+	err := invokeCommand(context.Background(), client, newFakeInvokeModel("rg", "aks", "helm uninstall ingress-nginx", true))
+
+	var policyErr *commandPolicyError
+	if !errors.As(err, &policyErr) {
+		t.Fatalf("expected a policy error, got %v", err)
+	}
+
+	if summary, _ := describeAzureError("Error while executing runCommand", err); summary != commandPolicyErrorSummary {
+		t.Errorf("summary = %q, want %q", summary, commandPolicyErrorSummary)
+	}
+
+	if requests := backend.Requests(); len(requests) != 0 {
+		t.Errorf("expected no runCommand request, got %d", len(requests))
+	}
+}
+
+func TestJobCommandLine(t *testing.T) {
+	data := JobModel{
+		Image:   types.StringValue("busybox:1.36"),
+		Command: types.ListValueMust(types.StringType, []attr.Value{types.StringValue("sh"), types.StringValue("-c")}),
+		Args:    types.ListValueMust(types.StringType, []attr.Value{types.StringValue("rm -rf /data")}),
+	}
+
+	if commandLine, ok := jobCommandLine(data); !ok || commandLine != "busybox:1.36 sh -c rm -rf /data" {
+		t.Errorf("jobCommandLine() = %q, %t", commandLine, ok)
+	}
+
+	data.Command = types.ListNull(types.StringType)
+	data.Args = types.ListValueMust(types.StringType, []attr.Value{types.StringUnknown()})
+
+	if _, ok := jobCommandLine(data); ok {
+		t.Error("expected an unknown command line")
+	}
+}
+
+func TestAccCommandPolicy(t *testing.T) {
+	const provider = `
+provider "azureakscommand" {
+  allowed_command_patterns = ["^uptime$", "^busybox:"]
+  denied_command_patterns  = ["rm\\s+-rf"]
+}
+`
+
+	tests := []struct {
+		name   string
+		config string
+		error  string
+	}{
+		{
+			// The policy applies to the command of the user, not to the script running it on the nodes.
+			name: "allowed node command",
+			config: `
+resource "azureakscommand_node_command" "test" {
+  resource_group_name = "rg"
+  name                = "plain"
+  command             = "uptime"
+}
+`,
+		},
+		{
+			name: "rejected node command",
+			config: `
+resource "azureakscommand_node_command" "test" {
+  resource_group_name = "rg"
+  name                = "plain"
+  command             = "journalctl -u kubelet"
+}
+`,
+			error: "none of the allowed_command_patterns",
+		},
+		{
+			name: "rejected job",
+			config: `
+resource "azureakscommand_job" "test" {
+  resource_group_name = "rg"
+  name                = "plain"
+  job_name            = "cleanup"
+  image               = "busybox:1.36"
+  command             = ["sh", "-c"]
+  args                = ["rm -rf /data"]
+}
+`,
+			error: "denied_command_patterns",
+		},
+		{
+			// The generated kubectl commands of azureakscommand_wait are checked like any other command.
+			name: "rejected wait",
+			config: `
+data "azureakscommand_wait" "test" {
+  resource_group_name = "rg"
+  name                = "plain"
+  resource            = "deployment/app"
+  rollout_status      = true
+}
+`,
+			error: "none of the allowed_command_patterns",
+		},
+		{
+			name: "rejected job image",
+			config: `
+resource "azureakscommand_job" "test" {
+  resource_group_name = "rg"
+  name                = "plain"
+  job_name            = "migrate"
+  image               = "example.com/migrate:1.0"
+}
+`,
+			error: "none of the allowed_command_patterns",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := newFakeBackend()
+
+			step := resource.TestStep{Config: provider + tt.config}
+			if tt.error != "" {
+				step.ExpectError = regexp.MustCompile(tt.error)
+				step.PlanOnly = true
+			}
+
+			resource.Test(t, resource.TestCase{
+				ProtoV6ProviderFactories: testAccProtoV6ProviderFactoriesWithFake(t, backend),
+				Steps:                    []resource.TestStep{step},
+			})
+
+			if requests := backend.Requests(); tt.error != "" && len(requests) != 0 {
+				t.Errorf("expected no runCommand request, got %d", len(requests))
+			}
+		})
+	}
+}
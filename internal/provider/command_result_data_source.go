@@ -0,0 +1,133 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ datasource.DataSource = &CommandResultDataSource{}
+
+func NewCommandResultDataSource() datasource.DataSource {
+	return &CommandResultDataSource{}
+}
+
+// CommandResultDataSource defines the data source implementation.
+type CommandResultDataSource struct {
+	data AzureAksCommandClient
+}
+
+// CommandResultDataSourceModel describes the data source data model.
+type CommandResultDataSourceModel struct {
+	Name              types.String `tfsdk:"name"`
+	ResourceGroupName types.String `tfsdk:"resource_group_name"`
+	CommandId         types.String `tfsdk:"command_id"`
+	CommandResultModel
+}
+
+func (d *CommandResultDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_command_result"
+}
+
+func (d *CommandResultDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "A data source to retrieve the current state of a runCommand execution on a AKS. " +
+			"It's recommended to use this data source together with `azureakscommand_invoke` resources with `wait = false`.",
+		Attributes: map[string]schema.Attribute{
+			"name": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "The name of the Managed Kubernetes Cluster.",
+			},
+			"resource_group_name": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "Specifies the Resource Group where the Managed Kubernetes Cluster exists.",
+			},
+			"command_id": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "The runCommand id, e.g. the `id` of an `azureakscommand_invoke` resource.",
+			},
+			"id": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "The runCommand id",
+			},
+			"exit_code": schema.Int64Attribute{
+				Computed:            true,
+				MarkdownDescription: "The exit code of the command",
+			},
+			"output": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "The output of the command",
+			},
+			"provisioning_state": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "provisioning state",
+			},
+			"provisioning_reason": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "An explanation of why provisioning_state is set to failed (if so).",
+			},
+			"started_at": schema.Int64Attribute{
+				Computed:            true,
+				MarkdownDescription: "The time as unix timestamp when the command started.",
+			},
+			"finished_at": schema.Int64Attribute{
+				Computed:            true,
+				MarkdownDescription: "The time as unix timestamp when the command finished.",
+			},
+		},
+	}
+}
+
+func (d *CommandResultDataSource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(AzureAksCommandClient)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected AzureAksCommandClient, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	d.data = data
+}
+
+func (d *CommandResultDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data *CommandResultDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	// Prevent panic if the provider has not been configured.
+	if d.data.managedClustersClient == nil {
+		resp.Diagnostics.AddError(
+			"Unconfigured Client",
+			"Expected configured client. Please report this issue to the provider developers.",
+		)
+	}
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	res, err := d.data.managedClustersClient.GetCommandResult(ctx, data.ResourceGroupName.ValueString(), data.Name.ValueString(), data.CommandId.ValueString(), nil)
+	if err != nil {
+		addAzureError(&resp.Diagnostics, "Error while retrieving runCommand result", err)
+		return
+	}
+
+	processRunCommand(&res.RunCommandResult, &data.CommandResultModel)
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
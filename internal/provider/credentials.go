@@ -0,0 +1,377 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/jkroepke/terraform-provider-azureakscommand/internal/clients"
+	"github.com/jkroepke/terraform-provider-azureakscommand/internal/helpers"
+)
+
+// hcpTerraformWorkloadIdentityToken is the environment variable, which holds the workload identity token of an HCP
+// Terraform run.
+const hcpTerraformWorkloadIdentityToken = "TFC_WORKLOAD_IDENTITY_TOKEN"
+
+// imdsProbeTimeout is the time, in which IMDS must answer to detect a managed identity, like in DefaultAzureCredential.
+const imdsProbeTimeout = time.Second
+
+// imdsEndpoint is the token endpoint of the Azure Instance Metadata Service, which is probed to detect a managed
+// identity.
+var imdsEndpoint = "http://169.254.169.254/metadata/identity/oauth2/token"
+
+// credentialConfig holds the authentication settings of a single provider instance, resolved from the provider
+// configuration and the environment. It is never written back to the process environment, so multiple provider
+// instances can use different identities.
+type credentialConfig struct {
+	TenantId                  string
+	ClientId                  string
+	ClientSecret              string
+	ClientCertificatePath     string
+	ClientCertificatePassword string
+	UseOidc                   bool
+	OidcToken                 string
+	OidcTokenFilePath         string
+	OidcRequestUrl            string
+	OidcRequestToken          string
+	OidcServiceConnectionId   string
+	UseMsi                    bool
+	DetectMsi                 bool
+	MsiEndpoint               string
+	MsiObjectId               string
+	MsiResourceId             string
+	UseCli                    bool
+	UseAzd                    bool
+	AuxiliaryTenantIds        []string
+	HttpClient                *http.Client
+}
+
+func newCredentialConfig(data AzureAksCommandProviderModel) credentialConfig {
+	return credentialConfig{
+		TenantId:                  getStringAttributeFromEnvironment(data.TenantId, []string{"ARM_TENANT_ID", "AZURE_TENANT_ID"}, ""),
+		ClientId:                  getStringAttributeFromEnvironment(data.ClientId, []string{"ARM_CLIENT_ID", "AZURE_CLIENT_ID"}, ""),
+		ClientSecret:              getStringAttributeFromEnvironment(data.ClientSecret, []string{"ARM_CLIENT_SECRET", "AZURE_CLIENT_SECRET"}, ""),
+		ClientCertificatePath:     getStringAttributeFromEnvironment(data.ClientCertificatePath, []string{"ARM_CLIENT_CERTIFICATE_PATH", "AZURE_CERTIFICATE_PATH", "AZURE_CLIENT_CERTIFICATE_PATH"}, ""),
+		ClientCertificatePassword: getStringAttributeFromEnvironment(data.ClientCertificatePassword, []string{"ARM_CLIENT_CERTIFICATE_PASSWORD", "AZURE_CERTIFICATE_PASSWORD", "AZURE_CLIENT_CERTIFICATE_PASSWORD"}, ""),
+		UseOidc:                   getBooleanAttributeFromEnvironment(data.UseOidc, []string{"ARM_USE_OIDC"}, false),
+		OidcToken:                 getStringAttributeFromEnvironment(data.OidcToken, []string{"ARM_OIDC_TOKEN"}, ""),
+		OidcTokenFilePath:         getStringAttributeFromEnvironment(data.OidcTokenFilePath, []string{"ARM_OIDC_TOKEN_FILE_PATH", "AZURE_FEDERATED_TOKEN_FILE"}, ""),
+		OidcRequestUrl:            getStringAttributeFromEnvironment(data.OidcRequestUrl, []string{"ARM_OIDC_REQUEST_URL", "ACTIONS_ID_TOKEN_REQUEST_URL", "SYSTEM_OIDCREQUESTURI"}, ""),
+		OidcRequestToken:          getStringAttributeFromEnvironment(data.OidcRequestToken, []string{"ARM_OIDC_REQUEST_TOKEN", "ACTIONS_ID_TOKEN_REQUEST_TOKEN", "SYSTEM_ACCESSTOKEN"}, ""),
+		OidcServiceConnectionId:   getStringAttributeFromEnvironment(data.OidcAzureServiceConnectionId, []string{"ARM_OIDC_AZURE_SERVICE_CONNECTION_ID", "ARM_ADO_PIPELINE_SERVICE_CONNECTION_ID"}, ""),
+		UseMsi:                    getBooleanAttributeFromEnvironment(data.UseMsi, []string{"ARM_USE_MSI"}, false),
+		DetectMsi:                 data.UseMsi.IsNull() && os.Getenv("ARM_USE_MSI") == "",
+		MsiEndpoint:               getStringAttributeFromEnvironment(data.MsiEndpoint, []string{"ARM_MSI_ENDPOINT", "MSI_ENDPOINT"}, ""),
+		MsiObjectId:               getStringAttributeFromEnvironment(data.MsiObjectId, []string{"ARM_MSI_OBJECT_ID"}, ""),
+		MsiResourceId:             getStringAttributeFromEnvironment(data.MsiResourceId, []string{"ARM_MSI_RESOURCE_ID"}, ""),
+		UseCli:                    getBooleanAttributeFromEnvironment(data.UseCli, []string{"ARM_USE_CLI"}, true),
+		UseAzd:                    getBooleanAttributeFromEnvironment(data.UseAzd, []string{"ARM_USE_AZD"}, false),
+		AuxiliaryTenantIds:        getStringListAttributeFromEnvironment(data.AuxiliaryTenantIds, []string{"ARM_AUXILIARY_TENANT_IDS"}, ";"),
+	}
+}
+
+// ClusterCredentialModel describes the cluster_credential block of the provider.
+type ClusterCredentialModel struct {
+	TenantId                  types.String `tfsdk:"tenant_id"`
+	ClientId                  types.String `tfsdk:"client_id"`
+	ClientSecret              types.String `tfsdk:"client_secret"`
+	ClientCertificatePath     types.String `tfsdk:"client_certificate_path"`
+	ClientCertificatePassword types.String `tfsdk:"client_certificate_password"`
+	OidcToken                 types.String `tfsdk:"oidc_token"`
+	OidcTokenFilePath         types.String `tfsdk:"oidc_token_file_path"`
+	UseOidc                   types.Bool   `tfsdk:"use_oidc"`
+	UseMsi                    types.Bool   `tfsdk:"use_msi"`
+	UseCli                    types.Bool   `tfsdk:"use_cli"`
+}
+
+// newClusterCredentialConfig resolves the cluster_credential block. Other than the provider attributes, the block is
+// not sourced from the environment, since the environment describes the identity used for ARM. The tenant, the
+// auxiliary tenants and the MSI endpoint are inherited from the provider.
+func newClusterCredentialConfig(data ClusterCredentialModel, parent credentialConfig) credentialConfig {
+	return credentialConfig{
+		TenantId:                  stringValueOrDefault(data.TenantId, parent.TenantId),
+		ClientId:                  data.ClientId.ValueString(),
+		ClientSecret:              data.ClientSecret.ValueString(),
+		ClientCertificatePath:     data.ClientCertificatePath.ValueString(),
+		ClientCertificatePassword: data.ClientCertificatePassword.ValueString(),
+		UseOidc:                   data.UseOidc.ValueBool(),
+		OidcToken:                 data.OidcToken.ValueString(),
+		OidcTokenFilePath:         data.OidcTokenFilePath.ValueString(),
+		UseMsi:                    data.UseMsi.ValueBool(),
+		MsiEndpoint:               parent.MsiEndpoint,
+		UseCli:                    data.UseCli.ValueBool(),
+		AuxiliaryTenantIds:        parent.AuxiliaryTenantIds,
+		HttpClient:                parent.HttpClient,
+	}
+}
+
+// buildTokenCredential returns a chain of all credentials enabled by the configuration. They are tried in the order
+// client certificate, client secret, OIDC, managed identity, Azure CLI and Azure Developer CLI. If use_msi isn't
+// configured and no credential precedes it, the managed identity is added when it's available, like in the discovery
+// of DefaultAzureCredential. Beside the chain, the names of the configured credentials are returned.
+func buildTokenCredential(config credentialConfig, clientOptions azcore.ClientOptions) (azcore.TokenCredential, []string, error) {
+	var sources []azcore.TokenCredential
+	var names []string
+
+	addSource := func(name string, cred azcore.TokenCredential) {
+		sources = append(sources, &namedCredential{name: name, credential: cred})
+		names = append(names, name)
+	}
+
+	if config.ClientCertificatePath != "" {
+		cred, err := newClientCertificateCredential(config, clientOptions)
+		if err != nil {
+			return nil, nil, fmt.Errorf("configuring client certificate authentication: %w", err)
+		}
+
+		addSource("ClientCertificateCredential", cred)
+	}
+
+	if config.ClientSecret != "" {
+		cred, err := azidentity.NewClientSecretCredential(config.TenantId, config.ClientId, config.ClientSecret, &azidentity.ClientSecretCredentialOptions{
+			ClientOptions:              clientOptions,
+			AdditionallyAllowedTenants: config.AuxiliaryTenantIds,
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("configuring client secret authentication: %w", err)
+		}
+
+		addSource("ClientSecretCredential", cred)
+	}
+
+	if config.UseOidc || config.OidcTokenFilePath != "" {
+		cred, err := newOidcCredential(config, clientOptions)
+		if err != nil {
+			return nil, nil, fmt.Errorf("configuring OIDC authentication: %w", err)
+		}
+
+		addSource("ClientAssertionCredential", cred)
+	}
+
+	if config.UseMsi || (config.DetectMsi && len(sources) == 0 && managedIdentityAvailable(config)) {
+		cred, err := newManagedIdentityCredential(config, clientOptions)
+		if err != nil {
+			return nil, nil, fmt.Errorf("configuring managed identity authentication: %w", err)
+		}
+
+		addSource("ManagedIdentityCredential", cred)
+	}
+
+	if config.UseCli {
+		cred, err := azidentity.NewAzureCLICredential(&azidentity.AzureCLICredentialOptions{
+			TenantID:                   config.TenantId,
+			AdditionallyAllowedTenants: config.AuxiliaryTenantIds,
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("configuring Azure CLI authentication: %w", err)
+		}
+
+		addSource("AzureCLICredential", cred)
+	}
+
+	if config.UseAzd {
+		cred, err := azidentity.NewAzureDeveloperCLICredential(&azidentity.AzureDeveloperCLICredentialOptions{
+			TenantID:                   config.TenantId,
+			AdditionallyAllowedTenants: config.AuxiliaryTenantIds,
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("configuring Azure Developer CLI authentication: %w", err)
+		}
+
+		addSource("AzureDeveloperCLICredential", cred)
+	}
+
+	if len(sources) == 0 {
+		return nil, nil, errors.New("no authentication method is enabled, configure a client secret, client certificate, OIDC, managed identity, use_cli or use_azd")
+	}
+
+	cred, err := azidentity.NewChainedTokenCredential(sources, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return cred, names, nil
+}
+
+// selectedCredentialKey is the context key of the name of the credential, which acquired a token, see
+// contextWithSelectedCredential.
+type selectedCredentialKey struct{}
+
+// contextWithSelectedCredential returns a context, which records the name of the credential of the chain that
+// acquired a token with it.
+func contextWithSelectedCredential(ctx context.Context) (context.Context, *string) {
+	var name string
+
+	return context.WithValue(ctx, selectedCredentialKey{}, &name), &name
+}
+
+// namedCredential logs the name of the wrapped credential once it acquired its first token, so the logs show which
+// credential of the chain was selected.
+type namedCredential struct {
+	name       string
+	credential azcore.TokenCredential
+	selected   sync.Once
+}
+
+func (c *namedCredential) GetToken(ctx context.Context, options policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	token, err := c.credential.GetToken(ctx, options)
+	if err == nil {
+		if name, ok := ctx.Value(selectedCredentialKey{}).(*string); ok {
+			*name = c.name
+		}
+
+		c.selected.Do(func() {
+			tflog.Info(ctx, "Selected Azure credential", map[string]interface{}{
+				"credential": c.name,
+			})
+		})
+	}
+
+	return token, err
+}
+
+func newClientCertificateCredential(config credentialConfig, clientOptions azcore.ClientOptions) (azcore.TokenCredential, error) {
+	certData, err := os.ReadFile(config.ClientCertificatePath)
+	if err != nil {
+		return nil, fmt.Errorf("reading certificate %q: %w", config.ClientCertificatePath, err)
+	}
+
+	var password []byte
+	if config.ClientCertificatePassword != "" {
+		password = []byte(config.ClientCertificatePassword)
+	}
+
+	certs, key, err := azidentity.ParseCertificates(certData, password)
+	if err != nil {
+		return nil, fmt.Errorf("parsing certificate %q: %w", config.ClientCertificatePath, err)
+	}
+
+	return azidentity.NewClientCertificateCredential(config.TenantId, config.ClientId, certs, key, &azidentity.ClientCertificateCredentialOptions{
+		ClientOptions:              clientOptions,
+		AdditionallyAllowedTenants: config.AuxiliaryTenantIds,
+	})
+}
+
+// newOidcCredential returns a credential exchanging an ID token for an access token. The ID token is requested from
+// the token source on every token refresh, so short-lived ID tokens don't expire during long applies.
+func newOidcCredential(config credentialConfig, clientOptions azcore.ClientOptions) (azcore.TokenCredential, error) {
+	tokenSource, err := newOidcTokenSource(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return azidentity.NewClientAssertionCredential(config.TenantId, config.ClientId, tokenSource.Token, &azidentity.ClientAssertionCredentialOptions{
+		ClientOptions:              clientOptions,
+		AdditionallyAllowedTenants: config.AuxiliaryTenantIds,
+	})
+}
+
+// newOidcTokenSource selects the source of the ID token. The order is oidc_token, oidc_token_file_path, an Azure
+// DevOps service connection, the GitHub Actions OIDC provider and the HCP Terraform workload identity token.
+func newOidcTokenSource(config credentialConfig) (helpers.OidcTokenSource, error) {
+	switch {
+	case config.OidcToken != "":
+		return helpers.StaticOidcTokenSource{Value: config.OidcToken}, nil
+	case config.OidcTokenFilePath != "":
+		return helpers.FileOidcTokenSource{Path: config.OidcTokenFilePath}, nil
+	case config.OidcRequestUrl != "" && config.OidcRequestToken != "" && config.OidcServiceConnectionId != "":
+		return helpers.AzureDevOpsOidcTokenSource{
+			RequestUrl:          config.OidcRequestUrl,
+			RequestToken:        config.OidcRequestToken,
+			ServiceConnectionId: config.OidcServiceConnectionId,
+			HttpClient:          config.HttpClient,
+		}, nil
+	case config.OidcRequestUrl != "" && config.OidcRequestToken != "":
+		return helpers.GithubActionsOidcTokenSource{
+			RequestUrl:   config.OidcRequestUrl,
+			RequestToken: config.OidcRequestToken,
+			HttpClient:   config.HttpClient,
+		}, nil
+	case os.Getenv(hcpTerraformWorkloadIdentityToken) != "":
+		return helpers.EnvironmentOidcTokenSource{Name: hcpTerraformWorkloadIdentityToken}, nil
+	default:
+		return nil, errors.New("none of oidc_token, oidc_token_file_path, oidc_request_url and oidc_request_token or " + hcpTerraformWorkloadIdentityToken + " are configured")
+	}
+}
+
+// managedIdentityAvailable reports whether a managed identity endpoint is available. Endpoints announced through the
+// environment, e.g. by App Service, are trusted. IMDS must answer a probe request within imdsProbeTimeout.
+func managedIdentityAvailable(config credentialConfig) bool {
+	if config.MsiEndpoint != "" || os.Getenv("IDENTITY_ENDPOINT") != "" {
+		return true
+	}
+
+	httpClient := config.HttpClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), imdsProbeTimeout)
+	defer cancel()
+
+	// Like DefaultAzureCredential, the probe omits the Metadata header. IMDS rejects it, but any answer proves that
+	// the endpoint is available.
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, imdsEndpoint, nil)
+	if err != nil {
+		return false
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return false
+	}
+
+	resp.Body.Close()
+
+	return true
+}
+
+// newManagedIdentityCredential returns a credential for the system-assigned identity, or the user-assigned identity
+// selected by client_id, msi_object_id or msi_resource_id.
+func newManagedIdentityCredential(config credentialConfig, clientOptions azcore.ClientOptions) (azcore.TokenCredential, error) {
+	options := &azidentity.ManagedIdentityCredentialOptions{
+		ClientOptions: clientOptions,
+	}
+
+	var ids []string
+
+	if config.ClientId != "" {
+		options.ID = azidentity.ClientID(config.ClientId)
+		ids = append(ids, "client_id")
+	}
+
+	if config.MsiObjectId != "" {
+		options.ID = azidentity.ObjectID(config.MsiObjectId)
+		ids = append(ids, "msi_object_id")
+	}
+
+	if config.MsiResourceId != "" {
+		options.ID = azidentity.ResourceID(config.MsiResourceId)
+		ids = append(ids, "msi_resource_id")
+	}
+
+	if len(ids) > 1 {
+		return nil, fmt.Errorf("only one of client_id, msi_object_id or msi_resource_id can be configured to select a user-assigned identity, got %s", strings.Join(ids, ", "))
+	}
+
+	if config.MsiEndpoint != "" {
+		msiEndpointPolicy, err := clients.WithMsiEndpoint(config.MsiEndpoint)
+		if err != nil {
+			return nil, fmt.Errorf("parsing msi_endpoint %q: %w", config.MsiEndpoint, err)
+		}
+
+		options.PerCallPolicies = append(append([]policy.Policy{}, clientOptions.PerCallPolicies...), msiEndpointPolicy)
+	}
+
+	return azidentity.NewManagedIdentityCredential(options)
+}
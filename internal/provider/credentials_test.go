@@ -0,0 +1,295 @@
+package provider
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func TestBuildTokenCredentialDetectsManagedIdentity(t *testing.T) {
+	t.Setenv("IDENTITY_ENDPOINT", "")
+
+	imds := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer imds.Close()
+
+	unavailable := httptest.NewServer(http.NotFoundHandler())
+	unavailable.Close()
+
+	tests := []struct {
+		name     string
+		endpoint string
+		config   credentialConfig
+		want     []string
+	}{
+		{
+			name:     "available",
+			endpoint: imds.URL,
+			config:   credentialConfig{DetectMsi: true, UseCli: true},
+			want:     []string{"ManagedIdentityCredential", "AzureCLICredential"},
+		},
+		{
+			name:     "unavailable",
+			endpoint: unavailable.URL,
+			config:   credentialConfig{DetectMsi: true, UseCli: true},
+			want:     []string{"AzureCLICredential"},
+		},
+		{
+			name:     "preceded by client secret",
+			endpoint: imds.URL,
+			config:   credentialConfig{DetectMsi: true, UseCli: true, TenantId: "11111111-1111-1111-1111-111111111111", ClientId: "22222222-2222-2222-2222-222222222222", ClientSecret: "secret"},
+			want:     []string{"ClientSecretCredential", "AzureCLICredential"},
+		},
+		{
+			name:     "disabled",
+			endpoint: imds.URL,
+			config:   credentialConfig{UseCli: true},
+			want:     []string{"AzureCLICredential"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := imdsEndpoint
+			imdsEndpoint = tt.endpoint
+			t.Cleanup(func() { imdsEndpoint = previous })
+
+			_, names, err := buildTokenCredential(tt.config, azcore.ClientOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(names, tt.want) {
+				t.Errorf("credentials = %v, want %v", names, tt.want)
+			}
+		})
+	}
+}
+
+// writeTestCertificate writes a self-signed certificate and its private key as PEM file.
+func writeTestCertificate(t *testing.T) string {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "azureakscommand"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	privateKey, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content := append(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert}), pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateKey})...)
+
+	path := filepath.Join(t.TempDir(), "client.pem")
+	if err := os.WriteFile(path, content, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestBuildTokenCredentialOrder(t *testing.T) {
+	certificatePath := writeTestCertificate(t)
+
+	const (
+		tenantId = "11111111-1111-1111-1111-111111111111"
+		clientId = "22222222-2222-2222-2222-222222222222"
+	)
+
+	all := credentialConfig{
+		TenantId:              tenantId,
+		ClientId:              clientId,
+		ClientSecret:          "secret",
+		ClientCertificatePath: certificatePath,
+		UseOidc:               true,
+		OidcToken:             "id-token",
+		UseMsi:                true,
+		UseCli:                true,
+		UseAzd:                true,
+	}
+
+	tests := []struct {
+		name    string
+		config  credentialConfig
+		want    []string
+		wantErr string
+	}{
+		{
+			name:   "all",
+			config: all,
+			want:   []string{"ClientCertificateCredential", "ClientSecretCredential", "ClientAssertionCredential", "ManagedIdentityCredential", "AzureCLICredential", "AzureDeveloperCLICredential"},
+		},
+		{
+			name:   "client secret and cli",
+			config: credentialConfig{TenantId: tenantId, ClientId: clientId, ClientSecret: "secret", UseCli: true},
+			want:   []string{"ClientSecretCredential", "AzureCLICredential"},
+		},
+		{
+			name:   "workload identity",
+			config: credentialConfig{TenantId: tenantId, ClientId: clientId, OidcTokenFilePath: "/var/run/secrets/azure/tokens/azure-identity-token"},
+			want:   []string{"ClientAssertionCredential"},
+		},
+		{
+			name:   "user-assigned managed identity and azd",
+			config: credentialConfig{ClientId: clientId, UseMsi: true, UseAzd: true},
+			want:   []string{"ManagedIdentityCredential", "AzureDeveloperCLICredential"},
+		},
+		{
+			name:   "cluster credential",
+			config: newClusterCredentialConfig(ClusterCredentialModel{ClientId: types.StringValue(clientId), ClientSecret: types.StringValue("secret")}, all),
+			want:   []string{"ClientSecretCredential"},
+		},
+		{
+			name:    "nothing",
+			config:  credentialConfig{},
+			wantErr: "no authentication method is enabled",
+		},
+		{
+			name:    "missing certificate",
+			config:  credentialConfig{TenantId: tenantId, ClientId: clientId, ClientCertificatePath: filepath.Join(t.TempDir(), "missing.pem")},
+			wantErr: "configuring client certificate authentication",
+		},
+		{
+			name:    "oidc without token",
+			config:  credentialConfig{TenantId: tenantId, ClientId: clientId, UseOidc: true},
+			wantErr: "configuring OIDC authentication",
+		},
+	}
+
+	t.Setenv(hcpTerraformWorkloadIdentityToken, "")
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, names, err := buildTokenCredential(tt.config, azcore.ClientOptions{})
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(names, tt.want) {
+				t.Errorf("credentials = %v, want %v", names, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewManagedIdentityCredentialValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  credentialConfig
+		wantErr string
+	}{
+		{name: "system-assigned", config: credentialConfig{}},
+		{name: "client_id", config: credentialConfig{ClientId: "22222222-2222-2222-2222-222222222222"}},
+		{name: "msi_object_id", config: credentialConfig{MsiObjectId: "33333333-3333-3333-3333-333333333333"}},
+		{name: "msi_resource_id", config: credentialConfig{MsiResourceId: "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg/providers/Microsoft.ManagedIdentity/userAssignedIdentities/id"}},
+		{
+			name:    "client_id and msi_object_id",
+			config:  credentialConfig{ClientId: "22222222-2222-2222-2222-222222222222", MsiObjectId: "33333333-3333-3333-3333-333333333333"},
+			wantErr: "got client_id, msi_object_id",
+		},
+		{
+			name:    "msi_object_id and msi_resource_id",
+			config:  credentialConfig{MsiObjectId: "33333333-3333-3333-3333-333333333333", MsiResourceId: "/subscriptions/00000000-0000-0000-0000-000000000000"},
+			wantErr: "got msi_object_id, msi_resource_id",
+		},
+		{
+			name:    "invalid msi_endpoint",
+			config:  credentialConfig{MsiEndpoint: "http://[::1"},
+			wantErr: "parsing msi_endpoint",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newManagedIdentityCredential(tt.config, azcore.ClientOptions{})
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestBuildTokenCredentialSelectsCredential(t *testing.T) {
+	t.Setenv("IDENTITY_ENDPOINT", "")
+
+	msi := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata") != "true" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"access_token":"msi-token","expires_in":"3600","expires_on":"%d","resource":"https://management.azure.com","token_type":"Bearer"}`, time.Now().Add(time.Hour).Unix())
+	}))
+	defer msi.Close()
+
+	cred, names, err := buildTokenCredential(credentialConfig{UseMsi: true, MsiEndpoint: msi.URL, UseCli: true}, azcore.ClientOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(names, []string{"ManagedIdentityCredential", "AzureCLICredential"}) {
+		t.Fatalf("unexpected credentials %v", names)
+	}
+
+	ctx, selected := contextWithSelectedCredential(context.Background())
+
+	token, err := cred.GetToken(ctx, policy.TokenRequestOptions{Scopes: []string{"https://management.azure.com/.default"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if token.Token != "msi-token" || *selected != "ManagedIdentityCredential" {
+		t.Errorf("unexpected token %q of credential %q", token.Token, *selected)
+	}
+}
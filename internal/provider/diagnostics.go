@@ -0,0 +1,251 @@
+package provider
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+)
+
+const diagnosticsTestCommand = "true"
+
+var (
+	// diagnosticsEnvironmentRegexp matches the Environment Variables, which influence the configuration of the provider.
+	diagnosticsEnvironmentRegexp = regexp.MustCompile(`^(ARM_|AZURE_|ACTIONS_ID_TOKEN_REQUEST_|TFC_WORKLOAD_IDENTITY_TOKEN|SYSTEM_OIDCREQUESTURI$|SYSTEM_ACCESSTOKEN$|MSI_ENDPOINT$|HTTPS?_PROXY$|NO_PROXY$)`)
+
+	// diagnosticsSecretRegexp matches the Environment Variables, whose values are redacted.
+	diagnosticsSecretRegexp = regexp.MustCompile(`SECRET|TOKEN|PASSWORD|ASSERTION|KEY`)
+
+	// diagnosticsProxyRegexp matches the Environment Variables, whose values are proxy URLs. Their userinfo is redacted.
+	diagnosticsProxyRegexp = regexp.MustCompile(`^HTTPS?_PROXY$`)
+)
+
+// diagnosticsReport describes which identity the provider uses and whether it may run commands on a cluster. Failed
+// checks are collected in Errors, so the remaining checks are still reported.
+type diagnosticsReport struct {
+	Environment         map[string]string
+	Credential          string
+	ObjectId            string
+	TenantId            string
+	ApplicationId       string
+	ClusterAadMode      string
+	ClusterAzureRbac    bool
+	RunCommandDisabled  bool
+	TestCommandExitCode *int64
+	Errors              []string
+}
+
+// runDiagnostics checks the identity of the provider and the cluster. If runTestCommand is true, the command `true`
+// is executed on the cluster.
+func runDiagnostics(ctx context.Context, client AzureAksCommandClient, resourceGroup string, resourceName string, runTestCommand bool) diagnosticsReport {
+	report := diagnosticsReport{
+		Environment: diagnosticsEnvironment(),
+	}
+
+	scope := client.resourceManagerScope
+	if scope == "" {
+		scope = "https://management.azure.com/.default"
+	}
+
+	tokenCtx, credential := contextWithSelectedCredential(ctx)
+
+	token, err := client.tokenCredential.GetToken(tokenCtx, policy.TokenRequestOptions{Scopes: []string{scope}})
+	if err != nil {
+		report.Errors = append(report.Errors, fmt.Sprintf("acquiring token for %s: %s", scope, err))
+	} else {
+		report.Credential = *credential
+
+		claims, err := tokenClaims(token.Token)
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("decoding token: %s", err))
+		}
+
+		report.ObjectId, report.TenantId, report.ApplicationId = claims.ObjectId, claims.TenantId, claims.ApplicationId
+		if report.ApplicationId == "" {
+			report.ApplicationId = claims.AuthorizedParty
+		}
+	}
+
+	res, err := client.managedClustersClient.Get(ctx, resourceGroup, resourceName, nil)
+	if err != nil {
+		summary, detail := describeAzureError(fmt.Sprintf("retrieving Managed Cluster %q (Resource Group %q)", resourceName, resourceGroup), err)
+		report.Errors = append(report.Errors, fmt.Sprintf("%s: %s", summary, detail))
+		return report
+	}
+
+	properties := res.Properties
+	switch {
+	case isAADManagedCluster(res.ManagedCluster):
+		report.ClusterAadMode = "managed"
+	case properties != nil && properties.AADProfile != nil:
+		report.ClusterAadMode = "legacy"
+	default:
+		report.ClusterAadMode = "disabled"
+	}
+
+	if properties != nil && properties.AADProfile != nil && properties.AADProfile.EnableAzureRBAC != nil {
+		report.ClusterAzureRbac = *properties.AADProfile.EnableAzureRBAC
+	}
+
+	if properties != nil && properties.APIServerAccessProfile != nil && properties.APIServerAccessProfile.DisableRunCommand != nil {
+		report.RunCommandDisabled = *properties.APIServerAccessProfile.DisableRunCommand
+	}
+
+	if !runTestCommand {
+		return report
+	}
+
+	if report.RunCommandDisabled {
+		report.Errors = append(report.Errors, "runCommand is disabled on the cluster (apiServerAccessProfile.disableRunCommand)")
+		return report
+	}
+
+	if err := client.commandPolicy.check(diagnosticsTestCommand); err != nil {
+		report.Errors = append(report.Errors, fmt.Sprintf("%s: %s", commandPolicyErrorSummary, err))
+		return report
+	}
+
+	commandResult, err := runCommand(ctx, client, resourceGroup, resourceName, diagnosticsTestCommand, "")
+	if err != nil {
+		summary, detail := describeAzureError(fmt.Sprintf("executing %q", diagnosticsTestCommand), err)
+		report.Errors = append(report.Errors, fmt.Sprintf("%s: %s", summary, detail))
+		return report
+	}
+
+	var result CommandResultModel
+
+	processRunCommand(&commandResult.RunCommandResult, &result)
+
+	if !result.ExitCode.IsNull() {
+		exitCode := result.ExitCode.ValueInt64()
+		report.TestCommandExitCode = &exitCode
+	}
+
+	for _, d := range commandResultWarnings(result) {
+		report.Errors = append(report.Errors, fmt.Sprintf("%s: %s", d.Summary(), d.Detail()))
+	}
+
+	if state := result.ProvisioningState.ValueString(); state != "Succeeded" {
+		report.Errors = append(report.Errors, fmt.Sprintf("executing %q: provisioning state %q: %s", diagnosticsTestCommand, state, result.ProvisioningReason.ValueString()))
+	}
+
+	return report
+}
+
+// diagnosticsEnvironment returns the Environment Variables relevant for the provider. Values of secrets and the
+// credentials of proxy URLs are redacted.
+func diagnosticsEnvironment() map[string]string {
+	environment := map[string]string{}
+
+	for _, env := range os.Environ() {
+		name, value, _ := strings.Cut(env, "=")
+		if !diagnosticsEnvironmentRegexp.MatchString(name) {
+			continue
+		}
+
+		if diagnosticsSecretRegexp.MatchString(name) && value != "" {
+			value = "(redacted)"
+		} else if diagnosticsProxyRegexp.MatchString(name) {
+			value = redactProxyUrl(value)
+		}
+
+		environment[name] = value
+	}
+
+	return environment
+}
+
+// redactProxyUrl replaces the userinfo of a proxy URL. Like net/http, URLs without scheme are parsed as http URLs.
+// Values, which can't be parsed, are redacted completely.
+func redactProxyUrl(value string) string {
+	if value == "" {
+		return value
+	}
+
+	proxyUrl, err := url.Parse(value)
+	if err != nil || proxyUrl.Scheme == "" || proxyUrl.Host == "" {
+		proxyUrl, err = url.Parse("http://" + value)
+		if err != nil {
+			return "(redacted)"
+		}
+	}
+
+	if proxyUrl.User == nil {
+		return value
+	}
+
+	proxyUrl.User = url.User("redacted")
+
+	return proxyUrl.String()
+}
+
+// String formats the report for the doctor subcommand.
+func (r diagnosticsReport) String() string {
+	var b strings.Builder
+
+	names := make([]string, 0, len(r.Environment))
+	for name := range r.Environment {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	b.WriteString("Environment:\n")
+
+	for _, name := range names {
+		fmt.Fprintf(&b, "  %s=%s\n", name, r.Environment[name])
+	}
+
+	fmt.Fprintf(&b, "Credential:           %s\n", r.Credential)
+	fmt.Fprintf(&b, "Object ID:            %s\n", r.ObjectId)
+	fmt.Fprintf(&b, "Tenant ID:            %s\n", r.TenantId)
+	fmt.Fprintf(&b, "Application ID:       %s\n", r.ApplicationId)
+	fmt.Fprintf(&b, "Cluster AAD mode:     %s\n", r.ClusterAadMode)
+	fmt.Fprintf(&b, "Cluster Azure RBAC:   %t\n", r.ClusterAzureRbac)
+	fmt.Fprintf(&b, "runCommand disabled:  %t\n", r.RunCommandDisabled)
+
+	if r.TestCommandExitCode != nil {
+		fmt.Fprintf(&b, "Test command:         %q exited with %d\n", diagnosticsTestCommand, *r.TestCommandExitCode)
+	}
+
+	for _, err := range r.Errors {
+		fmt.Fprintf(&b, "Error: %s\n", err)
+	}
+
+	return b.String()
+}
+
+// accessTokenClaims contains the claims of an access token identifying the caller.
+type accessTokenClaims struct {
+	ObjectId        string `json:"oid"`
+	TenantId        string `json:"tid"`
+	ApplicationId   string `json:"appid"`
+	AuthorizedParty string `json:"azp"`
+}
+
+// tokenClaims decodes the claims of a JWT access token. The signature isn't verified.
+func tokenClaims(token string) (accessTokenClaims, error) {
+	var claims accessTokenClaims
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return claims, errors.New("the access token is not a JWT")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return claims, err
+	}
+
+	err = json.Unmarshal(payload, &claims)
+
+	return claims, err
+}
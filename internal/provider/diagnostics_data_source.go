@@ -0,0 +1,180 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ datasource.DataSource = &DiagnosticsDataSource{}
+
+func NewDiagnosticsDataSource() datasource.DataSource {
+	return &DiagnosticsDataSource{}
+}
+
+// DiagnosticsDataSource defines the data source implementation.
+type DiagnosticsDataSource struct {
+	data AzureAksCommandClient
+}
+
+// DiagnosticsDataSourceModel describes the data source data model.
+type DiagnosticsDataSourceModel struct {
+	Name                types.String `tfsdk:"name"`
+	ResourceGroupName   types.String `tfsdk:"resource_group_name"`
+	RunTestCommand      types.Bool   `tfsdk:"run_test_command"`
+	Environment         types.Map    `tfsdk:"environment"`
+	Credential          types.String `tfsdk:"credential"`
+	ObjectId            types.String `tfsdk:"object_id"`
+	TenantId            types.String `tfsdk:"tenant_id"`
+	ApplicationId       types.String `tfsdk:"application_id"`
+	ClusterAadMode      types.String `tfsdk:"cluster_aad_mode"`
+	ClusterAzureRbac    types.Bool   `tfsdk:"cluster_azure_rbac"`
+	RunCommandDisabled  types.Bool   `tfsdk:"run_command_disabled"`
+	TestCommandExitCode types.Int64  `tfsdk:"test_command_exit_code"`
+	Errors              types.List   `tfsdk:"errors"`
+}
+
+func (d *DiagnosticsDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_diagnostics"
+}
+
+func (d *DiagnosticsDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "A data source to troubleshoot authentication and permissions. It reports the identity used by the provider and whether it may run commands on a AKS. " +
+			"Failed checks don't fail the data source, they are reported in `errors`. The `doctor` subcommand of the provider binary runs the same checks without Terraform.",
+		Attributes: map[string]schema.Attribute{
+			"name": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "The name of the Managed Kubernetes Cluster.",
+			},
+			"resource_group_name": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "Specifies the Resource Group where the Managed Kubernetes Cluster exists.",
+			},
+			"run_test_command": schema.BoolAttribute{
+				Optional:            true,
+				MarkdownDescription: "Run the command `true` on the cluster to verify the permissions for runCommand. Defaults to `true`.",
+			},
+			"environment": schema.MapAttribute{
+				ElementType:         types.StringType,
+				Computed:            true,
+				MarkdownDescription: "The Environment Variables, which influence the configuration of the provider. Values of secrets and credentials in proxy URLs are redacted.",
+			},
+			"credential": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "The credential of the chain, which acquired the token, e.g. `AzureCLICredential`.",
+			},
+			"object_id": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "The object ID (`oid` claim) of the identity.",
+			},
+			"tenant_id": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "The tenant ID (`tid` claim) of the identity.",
+			},
+			"application_id": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "The application ID (`appid` or `azp` claim) of the identity.",
+			},
+			"cluster_aad_mode": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "The Azure AD integration of the cluster. Possible values are `managed`, `legacy` and `disabled`.",
+			},
+			"cluster_azure_rbac": schema.BoolAttribute{
+				Computed:            true,
+				MarkdownDescription: "Whether Azure RBAC is used for Kubernetes authorization.",
+			},
+			"run_command_disabled": schema.BoolAttribute{
+				Computed:            true,
+				MarkdownDescription: "Whether runCommand is disabled on the cluster.",
+			},
+			"test_command_exit_code": schema.Int64Attribute{
+				Computed:            true,
+				MarkdownDescription: "The exit code of the test command, if it was executed.",
+			},
+			"errors": schema.ListAttribute{
+				ElementType:         types.StringType,
+				Computed:            true,
+				MarkdownDescription: "The failed checks.",
+			},
+		},
+	}
+}
+
+func (d *DiagnosticsDataSource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(AzureAksCommandClient)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected AzureAksCommandClient, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	d.data = data
+}
+
+func (d *DiagnosticsDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data *DiagnosticsDataSourceModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	// Prevent panic if the provider has not been configured.
+	if d.data.managedClustersClient == nil {
+		resp.Diagnostics.AddError(
+			"Unconfigured Client",
+			"Expected configured client. Please report this issue to the provider developers.",
+		)
+	}
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	runTestCommand := data.RunTestCommand.IsNull() || data.RunTestCommand.ValueBool()
+
+	report := runDiagnostics(ctx, d.data, data.ResourceGroupName.ValueString(), data.Name.ValueString(), runTestCommand)
+
+	for _, err := range report.Errors {
+		tflog.Warn(ctx, "Diagnostics check failed", map[string]interface{}{
+			"error": err,
+		})
+	}
+
+	environment, diags := types.MapValueFrom(ctx, types.StringType, report.Environment)
+	resp.Diagnostics.Append(diags...)
+
+	errors, diags := types.ListValueFrom(ctx, types.StringType, report.Errors)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	data.Environment = environment
+	data.Errors = errors
+	data.Credential = stringValueOrNull(report.Credential)
+	data.ObjectId = stringValueOrNull(report.ObjectId)
+	data.TenantId = stringValueOrNull(report.TenantId)
+	data.ApplicationId = stringValueOrNull(report.ApplicationId)
+	data.ClusterAadMode = stringValueOrNull(report.ClusterAadMode)
+	data.ClusterAzureRbac = types.BoolValue(report.ClusterAzureRbac)
+	data.RunCommandDisabled = types.BoolValue(report.RunCommandDisabled)
+	data.TestCommandExitCode = types.Int64PointerValue(report.TestCommandExitCode)
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
@@ -0,0 +1,200 @@
+package provider
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+
+	"github.com/jkroepke/terraform-provider-azureakscommand/internal/clients"
+)
+
+// azureError is the error of a failed Azure request reduced to the fields relevant for diagnostics.
+type azureError struct {
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+// knownAzureError maps a common failure to a summary and an explanation how to resolve it.
+type knownAzureError struct {
+	summary string
+	hint    string
+	matches func(azureErr azureError, message string) bool
+}
+
+var knownAzureErrors = []knownAzureError{
+	{
+		summary: "Missing permission to run commands on the Managed Cluster",
+		hint:    "The identity of the provider requires the permission Microsoft.ContainerService/managedClusters/runcommand/action on the cluster. Use the doctor subcommand or the azureakscommand_diagnostics data source to find out which identity is used.",
+		matches: func(azureErr azureError, message string) bool {
+			return azureErr.StatusCode == http.StatusForbidden && strings.Contains(strings.ToLower(message), "runcommand/action")
+		},
+	},
+	{
+		summary: "Missing permission on the Managed Cluster",
+		hint:    "The identity of the provider requires at least the permissions Microsoft.ContainerService/managedClusters/read and Microsoft.ContainerService/managedClusters/runcommand/action on the cluster.",
+		matches: func(azureErr azureError, _ string) bool {
+			return azureErr.StatusCode == http.StatusForbidden && strings.EqualFold(azureErr.Code, "AuthorizationFailed")
+		},
+	},
+	{
+		summary: "Managed Cluster not found",
+		hint:    "Verify name, resource_group_name and the subscription_id of the provider.",
+		matches: func(azureErr azureError, _ string) bool {
+			return azureErr.StatusCode == http.StatusNotFound &&
+				(strings.EqualFold(azureErr.Code, "ResourceNotFound") || strings.EqualFold(azureErr.Code, "ResourceGroupNotFound"))
+		},
+	},
+	{
+		summary: "runCommand is disabled on the Managed Cluster",
+		hint:    "The cluster is configured with apiServerAccessProfile.disableRunCommand. Enable runCommand on the cluster, e.g. with `az aks command enable`.",
+		matches: func(azureErr azureError, message string) bool {
+			message = strings.ToLower(message)
+			return strings.Contains(message, "disableruncommand") || strings.Contains(message, "run command is disabled") ||
+				strings.Contains(message, "runcommand is disabled")
+		},
+	},
+	{
+		summary: "Missing consent for the AKS Azure AD server application",
+		hint:    "The identity of the provider isn't allowed to acquire the cluster token. An administrator of the tenant has to consent to the application of cluster_token_scope, or cluster_token_scope doesn't match the server application of the cluster.",
+		matches: func(_ azureError, message string) bool {
+			return strings.Contains(message, "AADSTS65001") || strings.Contains(message, "AADSTS650057") || strings.Contains(message, "AADSTS500011")
+		},
+	},
+	{
+		summary: "Invalid scope of the cluster token",
+		hint:    "The credential rejected cluster_token_scope, also with the /.default suffix. Set cluster_token_scope to the application ID of the AKS Azure AD server application, e.g. 6dae42f8-4368-4678-94ff-3960e28e3630.",
+		matches: func(_ azureError, message string) bool {
+			return strings.Contains(message, "AADSTS1002012")
+		},
+	},
+}
+
+// addAzureError adds an error diagnostic for err, see describeAzureError.
+func addAzureError(diags *diag.Diagnostics, summary string, err error) {
+	diags.AddError(describeAzureError(summary, err))
+}
+
+// describeAzureError returns the summary and detail of a diagnostic for err. Known failures get a clear summary and
+// an explanation how to resolve them, the given summary is kept as context in the detail.
+func describeAzureError(summary string, err error) (string, string) {
+	var policyErr *commandPolicyError
+	if errors.As(err, &policyErr) {
+		return commandPolicyErrorSummary, err.Error()
+	}
+
+	known, ok := matchKnownAzureError(err)
+	if !ok {
+		return summary, errorDetail(err)
+	}
+
+	return known.summary, fmt.Sprintf("%s\n\n%s: %s", known.hint, summary, errorDetail(err))
+}
+
+// errorDetail returns the message of err for diagnostics. Failed Azure requests are reduced to the error code and
+// message instead of the full response, and the request and correlation IDs are appended, so they can be passed to
+// Azure support.
+func errorDetail(err error) string {
+	detail := err.Error()
+
+	var respErr *azcore.ResponseError
+	if azureErr, ok := unwrapAzureError(err); ok && azureErr.Message != "" && errors.As(err, &respErr) {
+		// keep the context added by wrapping errors, e.g. "retrieving Managed Cluster ...: "
+		detail = strings.TrimSuffix(detail, respErr.Error()) + fmt.Sprintf("%s (HTTP %d): %s", azureErr.Code, azureErr.StatusCode, azureErr.Message)
+	}
+
+	if ids := clients.CorrelationIds(err); ids != "" {
+		return fmt.Sprintf("%s\n\n%s", detail, ids)
+	}
+
+	return detail
+}
+
+func matchKnownAzureError(err error) (knownAzureError, bool) {
+	azureErr, _ := unwrapAzureError(err)
+
+	message := err.Error()
+	if azureErr.Message != "" {
+		message = azureErr.Message
+	}
+
+	for _, known := range knownAzureErrors {
+		if known.matches(azureErr, message) {
+			return known, true
+		}
+	}
+
+	return knownAzureError{}, false
+}
+
+// unwrapAzureError extracts the code and message of an *azcore.ResponseError from the ARM error response.
+func unwrapAzureError(err error) (azureError, bool) {
+	var respErr *azcore.ResponseError
+	if !errors.As(err, &respErr) {
+		return azureError{}, false
+	}
+
+	azureErr := azureError{
+		StatusCode: respErr.StatusCode,
+		Code:       respErr.ErrorCode,
+	}
+
+	if respErr.RawResponse == nil {
+		return azureErr, true
+	}
+
+	body, err := runtime.Payload(respErr.RawResponse)
+	if err != nil || len(body) == 0 {
+		return azureErr, true
+	}
+
+	var payload struct {
+		Error *struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}
+
+	if json.Unmarshal(body, &payload) != nil {
+		return azureErr, true
+	}
+
+	if payload.Error != nil {
+		payload.Code, payload.Message = payload.Error.Code, payload.Error.Message
+	}
+
+	if azureErr.Code == "" {
+		azureErr.Code = payload.Code
+	}
+
+	azureErr.Message = payload.Message
+
+	return azureErr, true
+}
+
+// commandResultWarnings returns warnings for known failures in the output of a command, which finished with a
+// non-zero exit code.
+func commandResultWarnings(data CommandResultModel) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	if data.ExitCode.IsNull() || data.ExitCode.ValueInt64() == 0 {
+		return diags
+	}
+
+	if strings.Contains(data.Output.ValueString(), "Error from server (Forbidden)") {
+		diags.AddWarning("Kubernetes RBAC denied the command",
+			"The command was rejected by the Kubernetes API server. The identity of the provider needs a Kubernetes RoleBinding or, "+
+				"on clusters with Azure RBAC, an Azure Kubernetes Service RBAC role assignment. "+
+				"Use the doctor subcommand or the azureakscommand_diagnostics data source to find out which identity is used.")
+	}
+
+	return diags
+}
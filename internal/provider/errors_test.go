@@ -0,0 +1,95 @@
+package provider
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func newResponseError(statusCode int, body string) error {
+	req, _ := http.NewRequest(http.MethodPost, "https://management.azure.com/subscriptions/sub/resourceGroups/rg/providers/Microsoft.ContainerService/managedClusters/aks/runCommand", nil)
+
+	resp := &http.Response{
+		StatusCode: statusCode,
+		Header:     http.Header{"X-Ms-Request-Id": []string{"req-id"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}
+
+	return runtime.NewResponseError(resp)
+}
+
+func TestDescribeAzureError(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		summary string
+		detail  string
+	}{
+		{
+			name:    "missing runcommand permission",
+			err:     newResponseError(http.StatusForbidden, `{"error":{"code":"AuthorizationFailed","message":"The client 'x' does not have authorization to perform action 'Microsoft.ContainerService/managedClusters/runcommand/action'."}}`),
+			summary: "Missing permission to run commands on the Managed Cluster",
+			detail:  "AuthorizationFailed (HTTP 403): The client 'x' does not have authorization",
+		},
+		{
+			name:    "cluster not found",
+			err:     fmt.Errorf("retrieving Managed Cluster: %w", newResponseError(http.StatusNotFound, `{"error":{"code":"ResourceNotFound","message":"The Resource 'aks' was not found."}}`)),
+			summary: "Managed Cluster not found",
+			detail:  "retrieving Managed Cluster: ResourceNotFound (HTTP 404): The Resource 'aks' was not found.",
+		},
+		{
+			name:    "run command disabled",
+			err:     newResponseError(http.StatusBadRequest, `{"code":"OperationNotAllowed","message":"Run command is disabled for this cluster."}`),
+			summary: "runCommand is disabled on the Managed Cluster",
+			detail:  "OperationNotAllowed (HTTP 400)",
+		},
+		{
+			name:    "consent missing",
+			err:     fmt.Errorf("AADSTS65001: The user or administrator has not consented to use the application"),
+			summary: "Missing consent for the AKS Azure AD server application",
+		},
+		{
+			name:    "unknown error",
+			err:     newResponseError(http.StatusConflict, `{"error":{"code":"Conflict","message":"Another operation is in progress."}}`),
+			summary: "Error while executing runCommand",
+			detail:  "Conflict (HTTP 409): Another operation is in progress.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			summary, detail := describeAzureError("Error while executing runCommand", tt.err)
+
+			if summary != tt.summary {
+				t.Errorf("summary = %q, want %q", summary, tt.summary)
+			}
+
+			if !strings.Contains(detail, tt.detail) {
+				t.Errorf("detail = %q, want it to contain %q", detail, tt.detail)
+			}
+		})
+	}
+}
+
+func TestCommandResultWarnings(t *testing.T) {
+	data := CommandResultModel{
+		ExitCode: types.Int64Value(1),
+		Output:   types.StringValue(`Error from server (Forbidden): pods is forbidden: User "x" cannot list resource "pods"`),
+	}
+
+	if diags := commandResultWarnings(data); len(diags) != 1 {
+		t.Errorf("expected a warning, got %v", diags)
+	}
+
+	data.ExitCode = types.Int64Value(0)
+
+	if diags := commandResultWarnings(data); len(diags) != 0 {
+		t.Errorf("expected no warning for a successful command, got %v", diags)
+	}
+}
@@ -0,0 +1,327 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	azfake "github.com/Azure/azure-sdk-for-go/sdk/azcore/fake"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/containerservice/armcontainerservice/v9"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/containerservice/armcontainerservice/v9/fake"
+
+	"github.com/jkroepke/terraform-provider-azureakscommand/internal/clients"
+)
+
+const (
+	headerRetryAfterMs     = "Retry-After-Ms"
+	headerFakePollerStatus = "Fake-Poller-Status"
+)
+
+// FakeCluster describes a Managed Cluster served by FakeManagedClusters.
+type FakeCluster struct {
+	// AAD enables the AKS-managed Azure AD integration. runCommand requests against the cluster are rejected
+	// without cluster token.
+	AAD bool
+
+	// TenantID is the tenant of the Azure AD integration.
+	TenantID string
+}
+
+// FakeCommandResult describes the outcome of a command executed by FakeManagedClusters.
+type FakeCommandResult struct {
+	ExitCode int32
+	Logs     string
+
+	// ProvisioningState of the command, defaults to Succeeded. Reason explains a Failed state. Like in ARM, the
+	// runCommand operation of a Failed command fails.
+	ProvisioningState string
+	Reason            string
+
+	// Polls is the number of polls, which report the command as running before it finishes.
+	Polls int
+
+	// PollStatusCode and PollErrorCode fail all but the first of the Polls, e.g. with 403 and AuthorizationFailed.
+	PollStatusCode int
+	PollErrorCode  string
+
+	// StatusCode and ErrorCode reject the runCommand request, e.g. 409 and Conflict.
+	StatusCode int
+	ErrorCode  string
+
+	// Err fails the runCommand request with the error, without any response.
+	Err error
+}
+
+// FakeRunCommandRequest is a runCommand request received by FakeManagedClusters.
+type FakeRunCommandRequest struct {
+	ResourceGroup string
+	ResourceName  string
+	Request       armcontainerservice.RunCommandRequest
+}
+
+// FakeManagedClusters is an in-process ARM backend for Managed Clusters, built on the fake server of the Azure SDK.
+// It allows to run the provider offline, see NewClient.
+type FakeManagedClusters struct {
+	// Clusters contains the clusters of the backend by "resourceGroup/name". Requests against other clusters fail
+	// with ResourceNotFound. If Clusters is nil, every cluster exists without Azure AD integration.
+	Clusters map[string]FakeCluster
+
+	// Handler returns the outcome of a command. Without handler, all commands succeed with exit code 0.
+	Handler func(request FakeRunCommandRequest) FakeCommandResult
+
+	// PollInterval is the delay between polls of running commands, which is sent as Retry-After-Ms header.
+	// Defaults to 10ms.
+	PollInterval time.Duration
+
+	mu       sync.Mutex
+	requests []FakeRunCommandRequest
+	commands map[string]*fakeCommand
+}
+
+type fakeCommand struct {
+	result  armcontainerservice.RunCommandResult
+	pending int
+}
+
+// fakeCommandIDKey is the context key, through which the runCommand handler passes the ID of a new command to
+// fakeManagedClustersTransport.
+type fakeCommandIDKey struct{}
+
+// NewClient returns a ManagedClustersClient connected to the backend. Retries are disabled, so failures are
+// reported immediately.
+func (f *FakeManagedClusters) NewClient() (*armcontainerservice.ManagedClustersClient, error) {
+	server := &fake.ManagedClustersServer{
+		Get:              f.get,
+		BeginRunCommand:  f.beginRunCommand,
+		GetCommandResult: f.getCommandResult,
+	}
+
+	pollInterval := f.PollInterval
+	if pollInterval == 0 {
+		pollInterval = 10 * time.Millisecond
+	}
+
+	return armcontainerservice.NewManagedClustersClient(clients.FakeSubscriptionId, &azfake.TokenCredential{}, &arm.ClientOptions{
+		ClientOptions: policy.ClientOptions{
+			Transport: fakeManagedClustersTransport{
+				transport:    fake.NewManagedClustersServerTransport(server),
+				pollInterval: pollInterval,
+			},
+			Retry: policy.RetryOptions{MaxRetries: -1},
+		},
+	})
+}
+
+// Requests returns the runCommand requests received so far.
+func (f *FakeManagedClusters) Requests() []FakeRunCommandRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]FakeRunCommandRequest(nil), f.requests...)
+}
+
+func (f *FakeManagedClusters) cluster(resourceGroupName string, resourceName string) (FakeCluster, bool) {
+	if f.Clusters == nil {
+		return FakeCluster{}, true
+	}
+
+	cluster, ok := f.Clusters[resourceGroupName+"/"+resourceName]
+
+	return cluster, ok
+}
+
+func (f *FakeManagedClusters) get(_ context.Context, resourceGroupName string, resourceName string, _ *armcontainerservice.ManagedClustersClientGetOptions) (resp azfake.Responder[armcontainerservice.ManagedClustersClientGetResponse], errResp azfake.ErrorResponder) {
+	cluster, ok := f.cluster(resourceGroupName, resourceName)
+	if !ok {
+		errResp.SetResponseError(http.StatusNotFound, "ResourceNotFound")
+		return
+	}
+
+	managedCluster := armcontainerservice.ManagedCluster{
+		ID:         to.Ptr(fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.ContainerService/managedClusters/%s", clients.FakeSubscriptionId, resourceGroupName, resourceName)),
+		Name:       to.Ptr(resourceName),
+		Location:   to.Ptr("westeurope"),
+		Properties: &armcontainerservice.ManagedClusterProperties{},
+	}
+
+	if cluster.AAD {
+		managedCluster.Properties.AADProfile = &armcontainerservice.ManagedClusterAADProfile{
+			Managed:  to.Ptr(true),
+			TenantID: to.Ptr(cluster.TenantID),
+		}
+	}
+
+	resp.SetResponse(http.StatusOK, armcontainerservice.ManagedClustersClientGetResponse{ManagedCluster: managedCluster}, nil)
+
+	return
+}
+
+func (f *FakeManagedClusters) beginRunCommand(ctx context.Context, resourceGroupName string, resourceName string, requestPayload armcontainerservice.RunCommandRequest, _ *armcontainerservice.ManagedClustersClientBeginRunCommandOptions) (resp azfake.PollerResponder[armcontainerservice.ManagedClustersClientRunCommandResponse], errResp azfake.ErrorResponder) {
+	cluster, ok := f.cluster(resourceGroupName, resourceName)
+	if !ok {
+		errResp.SetResponseError(http.StatusNotFound, "ResourceNotFound")
+		return
+	}
+
+	if cluster.AAD && (requestPayload.ClusterToken == nil || *requestPayload.ClusterToken == "") {
+		errResp.SetResponseError(http.StatusBadRequest, "BadRequest")
+		return
+	}
+
+	request := FakeRunCommandRequest{
+		ResourceGroup: resourceGroupName,
+		ResourceName:  resourceName,
+		Request:       requestPayload,
+	}
+
+	outcome := FakeCommandResult{}
+	if f.Handler != nil {
+		outcome = f.Handler(request)
+	}
+
+	if outcome.Err != nil {
+		errResp.SetError(outcome.Err)
+		return
+	}
+
+	if outcome.StatusCode != 0 {
+		errResp.SetResponseError(outcome.StatusCode, outcome.ErrorCode)
+		return
+	}
+
+	if outcome.ProvisioningState == "" {
+		outcome.ProvisioningState = "Succeeded"
+	}
+
+	now := time.Now()
+
+	f.mu.Lock()
+	if f.commands == nil {
+		f.commands = map[string]*fakeCommand{}
+	}
+
+	commandID := fmt.Sprintf("%032x", len(f.requests)+1)
+	command := &fakeCommand{
+		result: armcontainerservice.RunCommandResult{
+			ID: to.Ptr(commandID),
+			Properties: &armcontainerservice.CommandResultProperties{
+				ExitCode:          to.Ptr(outcome.ExitCode),
+				Logs:              to.Ptr(outcome.Logs),
+				ProvisioningState: to.Ptr(outcome.ProvisioningState),
+				Reason:            to.Ptr(outcome.Reason),
+				StartedAt:         to.Ptr(now),
+				FinishedAt:        to.Ptr(now),
+			},
+		},
+		pending: outcome.Polls,
+	}
+
+	f.requests = append(f.requests, request)
+	f.commands[commandID] = command
+	f.mu.Unlock()
+
+	if id, ok := ctx.Value(fakeCommandIDKey{}).(*string); ok {
+		*id = commandID
+	}
+
+	for i := 0; i < outcome.Polls; i++ {
+		// The first response is the one of the runCommand request.
+		if i > 0 && outcome.PollStatusCode != 0 {
+			resp.AddPollingError(&azcore.ResponseError{StatusCode: outcome.PollStatusCode, ErrorCode: outcome.PollErrorCode})
+			continue
+		}
+
+		resp.AddNonTerminalResponse(http.StatusAccepted, nil)
+	}
+
+	resp.SetTerminalResponse(http.StatusOK, armcontainerservice.ManagedClustersClientRunCommandResponse{RunCommandResult: command.result}, nil)
+
+	return
+}
+
+func (f *FakeManagedClusters) getCommandResult(_ context.Context, _ string, _ string, commandID string, _ *armcontainerservice.ManagedClustersClientGetCommandResultOptions) (resp azfake.Responder[armcontainerservice.ManagedClustersClientGetCommandResultResponse], errResp azfake.ErrorResponder) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	command, ok := f.commands[commandID]
+	if !ok {
+		errResp.SetResponseError(http.StatusNotFound, "NotFound")
+		return
+	}
+
+	if command.pending > 0 {
+		command.pending--
+
+		resp.SetResponse(http.StatusAccepted, armcontainerservice.ManagedClustersClientGetCommandResultResponse{
+			RunCommandResult: armcontainerservice.RunCommandResult{
+				ID: command.result.ID,
+				Properties: &armcontainerservice.CommandResultProperties{
+					ProvisioningState: to.Ptr("Running"),
+				},
+			},
+		}, nil)
+
+		return
+	}
+
+	resp.SetResponse(http.StatusOK, armcontainerservice.ManagedClustersClientGetCommandResultResponse{RunCommandResult: command.result}, nil)
+
+	return
+}
+
+// fakeManagedClustersTransport completes the responses of the fake server, which lack the Location header of the
+// runCommand operation and a Retry-After for running commands. The fake poller reports every terminal response as
+// succeeded, while the location poller of ARM fails the operation on provisioningState Failed, so the state of
+// failed commands is corrected.
+type fakeManagedClustersTransport struct {
+	transport    policy.Transporter
+	pollInterval time.Duration
+}
+
+func (t fakeManagedClustersTransport) Do(req *http.Request) (*http.Response, error) {
+	var commandID string
+
+	resp, err := t.transport.Do(req.WithContext(context.WithValue(req.Context(), fakeCommandIDKey{}, &commandID)))
+	if err != nil {
+		return resp, err
+	}
+
+	if commandID != "" {
+		location := *req.URL
+		location.Path = strings.TrimSuffix(location.Path, "/runCommand") + "/commandResults/" + commandID
+		resp.Header.Set("Location", location.String())
+	}
+
+	if resp.StatusCode == http.StatusAccepted {
+		resp.Header.Set(headerRetryAfterMs, strconv.FormatInt(t.pollInterval.Milliseconds(), 10))
+	}
+
+	if resp.Header.Get(headerFakePollerStatus) != "" && resp.Body != nil {
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+
+		var result armcontainerservice.RunCommandResult
+		if json.Unmarshal(body, &result) == nil && result.Properties != nil && result.Properties.ProvisioningState != nil &&
+			*result.Properties.ProvisioningState == "Failed" {
+			resp.Header.Set(headerFakePollerStatus, "Failed")
+		}
+	}
+
+	return resp, nil
+}
@@ -6,11 +6,13 @@ import (
 
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
 	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 )
 
 // Ensure provider defined types fully satisfy framework interfaces.
 var _ datasource.DataSource = &InvokeDataSource{}
+var _ datasource.DataSourceWithValidateConfig = &InvokeDataSource{}
 
 func NewInvokeDataSource() datasource.DataSource {
 	return &InvokeDataSource{}
@@ -47,6 +49,14 @@ func (d *InvokeDataSource) Schema(_ context.Context, _ datasource.SchemaRequest,
 				Optional:            true,
 				MarkdownDescription: "A base64 encoded zip file containing the files required by the command.",
 			},
+			"wait": schema.BoolAttribute{
+				Optional:            true,
+				MarkdownDescription: "Wait until the command has finished. Only `true` is supported, since the data source is read on every plan and refresh, which would start a new command each time. Use the `azureakscommand_invoke` resource to start a command without waiting. Defaults to `true`.",
+			},
+			"lock_group": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "Commands of the same lock group are strictly serialized within a provider instance, e.g. commands touching the same component.",
+			},
 			"triggers": schema.MapAttribute{
 				Optional:            true,
 				MarkdownDescription: "A map of arbitrary strings that, when changed, will force the null resource to be replaced, re-running any associated provisioners.",
@@ -84,6 +94,22 @@ func (d *InvokeDataSource) Schema(_ context.Context, _ datasource.SchemaRequest,
 	}
 }
 
+func (d *InvokeDataSource) ValidateConfig(ctx context.Context, req datasource.ValidateConfigRequest, resp *datasource.ValidateConfigResponse) {
+	var wait types.Bool
+
+	resp.Diagnostics.Append(req.Config.GetAttribute(ctx, path.Root("wait"), &wait)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if !wait.IsNull() && !wait.IsUnknown() && !wait.ValueBool() {
+		resp.Diagnostics.AddAttributeError(path.Root("wait"), "Unsupported wait value",
+			"The data source is read on every plan and refresh, with wait = false each read would start a new command, whose result is never read. "+
+				"Use the azureakscommand_invoke resource to start a command without waiting.")
+	}
+}
+
 func (d *InvokeDataSource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
 	// Prevent panic if the provider has not been configured.
 	if req.ProviderData == nil {
@@ -121,13 +147,21 @@ func (d *InvokeDataSource) Read(ctx context.Context, req datasource.ReadRequest,
 	if resp.Diagnostics.HasError() {
 		return
 	}
-	runCommand, err := runCommand(ctx, d.data, data.ResourceGroupName.ValueString(), data.Name.ValueString(), data.Command.ValueString(), data.Context.ValueString())
+
+	addCommandPolicyError(&resp.Diagnostics, path.Root("command"), d.data, data.Command.ValueString())
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	err := invokeCommand(contextWithLockGroup(ctx, data.LockGroup.ValueString()), d.data, data)
 
 	if err != nil {
-		resp.Diagnostics.AddError("Error while executing runCommand", err.Error())
+		addAzureError(&resp.Diagnostics, "Error while executing runCommand", err)
+		return
 	}
 
-	processRunCommand(runCommand, data)
+	resp.Diagnostics.Append(commandResultWarnings(data.CommandResultModel)...)
 
 	// Save data into Terraform state
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
@@ -0,0 +1,83 @@
+package provider
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func TestAccInvokeDataSource(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactoriesWithFake(t, newFakeBackend()),
+		Steps: []resource.TestStep{
+			{
+				Config: `
+data "azureakscommand_invoke" "plain" {
+  resource_group_name = "rg"
+  name                = "plain"
+  command             = "echo hello"
+}
+
+data "azureakscommand_invoke" "aad" {
+  resource_group_name = "rg"
+  name                = "aad"
+  command             = "sleep"
+}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.azureakscommand_invoke.plain", "output", "hello\n"),
+					resource.TestCheckResourceAttr("data.azureakscommand_invoke.plain", "exit_code", "0"),
+					resource.TestCheckResourceAttr("data.azureakscommand_invoke.aad", "output", "done\n"),
+					resource.TestCheckResourceAttr("data.azureakscommand_invoke.aad", "provisioning_state", "Succeeded"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccInvokeDataSourceErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		error  string
+	}{
+		{
+			name: "unknown cluster",
+			config: `
+data "azureakscommand_invoke" "test" {
+  resource_group_name = "rg"
+  name                = "missing"
+  command             = "echo hello"
+}
+`,
+			error: "Managed Cluster not found",
+		},
+		{
+			name: "wait false",
+			config: `
+data "azureakscommand_invoke" "test" {
+  resource_group_name = "rg"
+  name                = "plain"
+  command             = "echo hello"
+  wait                = false
+}
+`,
+			error: "Unsupported wait value",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resource.Test(t, resource.TestCase{
+				ProtoV6ProviderFactories: testAccProtoV6ProviderFactoriesWithFake(t, newFakeBackend()),
+				Steps: []resource.TestStep{
+					{
+						Config:      tt.config,
+						ExpectError: regexp.MustCompile(tt.error),
+					},
+				},
+			})
+		})
+	}
+}
@@ -2,10 +2,16 @@ package provider
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/containerservice/armcontainerservice/v9"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/boolplanmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64planmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/mapplanmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
@@ -13,8 +19,23 @@ import (
 	"github.com/hashicorp/terraform-plugin-framework/types"
 )
 
+// privateStateKeyRunCommand is the private state key of a runCommand execution, which has not finished yet.
+const privateStateKeyRunCommand = "run_command"
+
 // Ensure provider defined types fully satisfy framework interfaces.
 var _ resource.Resource = &InvokeResource{}
+var _ resource.ResourceWithModifyPlan = &InvokeResource{}
+
+// runCommandPrivateState describes the private state data of a runCommand execution, which has not finished yet.
+type runCommandPrivateState struct {
+	ResumeToken string `json:"resume_token"`
+	CommandID   string `json:"command_id"`
+}
+
+// privateStateSetter is implemented by the private state of framework responses.
+type privateStateSetter interface {
+	SetKey(ctx context.Context, key string, value []byte) diag.Diagnostics
+}
 
 func NewInvokeResource() resource.Resource {
 	return &InvokeResource{}
@@ -31,6 +52,9 @@ func (r *InvokeResource) Metadata(_ context.Context, req resource.MetadataReques
 
 func (r *InvokeResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
 	description := "A resource to managed a runCommand execution on a AKS" +
+		"\n\n" +
+		"If waiting for the result is interrupted, e.g. by a transient error or a canceled apply, the command is stored as `Running` and its result is retrieved on the next refresh. " +
+		"If the provider is killed while waiting, the command isn't recorded in the state and is executed again by the next apply." +
 		"\n\n" +
 		"The `triggers` argument allows specifying an arbitrary set of values that, when changed, will cause the resource to be replaced."
 
@@ -67,6 +91,17 @@ func (r *InvokeResource) Schema(_ context.Context, _ resource.SchemaRequest, res
 					stringplanmodifier.RequiresReplace(),
 				},
 			},
+			"wait": schema.BoolAttribute{
+				Optional:            true,
+				MarkdownDescription: "Wait until the command has finished. If `false`, the command is only started and its result can be retrieved through the `azureakscommand_command_result` data source. Defaults to `true`. Changing this forces a new resource to be created.",
+				PlanModifiers: []planmodifier.Bool{
+					boolplanmodifier.RequiresReplace(),
+				},
+			},
+			"lock_group": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "Commands of the same lock group are strictly serialized within a provider instance, e.g. commands touching the same component.",
+			},
 			"triggers": schema.MapAttribute{
 				Optional:            true,
 				MarkdownDescription: "A map of arbitrary strings that, when changed, will force the null resource to be replaced, re-running any associated provisioners.",
@@ -98,7 +133,7 @@ func (r *InvokeResource) Schema(_ context.Context, _ resource.SchemaRequest, res
 			},
 			"provisioning_state": schema.StringAttribute{
 				Computed:            true,
-				MarkdownDescription: "provisioning state",
+				MarkdownDescription: "provisioning state. `Running`, if `wait` is `false` and the command has not finished yet.",
 				PlanModifiers: []planmodifier.String{
 					stringplanmodifier.UseStateForUnknown(),
 				},
@@ -148,6 +183,25 @@ func (r *InvokeResource) Configure(_ context.Context, req resource.ConfigureRequ
 	r.data = data
 }
 
+// ModifyPlan rejects commands, which are denied by the command policy of the provider, at plan time.
+func (r *InvokeResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	// Nothing is executed on destroy.
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var command types.String
+
+	resp.Diagnostics.Append(req.Plan.GetAttribute(ctx, path.Root("command"), &command)...)
+
+	// Unknown commands are checked by beginRunCommand before they are sent.
+	if resp.Diagnostics.HasError() || command.IsUnknown() || command.IsNull() {
+		return
+	}
+
+	addCommandPolicyError(&resp.Diagnostics, path.Root("command"), r.data, command.ValueString())
+}
+
 func (r *InvokeResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
 	var data *InvokeModel
 
@@ -166,17 +220,52 @@ func (r *InvokeResource) Create(ctx context.Context, req resource.CreateRequest,
 		return
 	}
 
-	runCommand, err := runCommand(ctx, r.data, data.ResourceGroupName.ValueString(), data.Name.ValueString(), data.Command.ValueString(), data.Context.ValueString())
+	// The command may have been unknown at plan time.
+	addCommandPolicyError(&resp.Diagnostics, path.Root("command"), r.data, data.Command.ValueString())
 
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	ctx = contextWithLockGroup(ctx, data.LockGroup.ValueString())
+
+	release, err := acquireCommand(ctx, r.data, data.ResourceGroupName.ValueString(), data.Name.ValueString())
 	if err != nil {
 		resp.Diagnostics.AddError("Error while executing runCommand", err.Error())
+		return
 	}
+	defer release()
 
-	if resp.Diagnostics.HasError() {
+	poller, commandID, err := beginRunCommand(ctx, r.data, data.ResourceGroupName.ValueString(), data.Name.ValueString(), data.Command.ValueString(), data.Context.ValueString())
+
+	if err != nil {
+		addAzureError(&resp.Diagnostics, "Error while executing runCommand", err)
 		return
 	}
 
-	processRunCommand(runCommand, data)
+	// Keep the poller in the private state, which is saved when Create returns. If polling gets interrupted, e.g. by a
+	// canceled context or a transient error, Read resumes it instead of executing the command a second time. A killed
+	// provider saves nothing, the command is executed again by the next apply in that case.
+	if resumeToken, err := poller.ResumeToken(); err == nil {
+		privateState, err := json.Marshal(runCommandPrivateState{ResumeToken: resumeToken, CommandID: commandID})
+		if err != nil {
+			resp.Diagnostics.AddError("Error while storing runCommand poller", err.Error())
+			return
+		}
+
+		resp.Diagnostics.Append(resp.Private.SetKey(ctx, privateStateKeyRunCommand, privateState)...)
+	}
+
+	diags, err := pollRunCommand(ctx, poller, commandID, data, resp.Private)
+	resp.Diagnostics.Append(diags...)
+
+	if err != nil {
+		addAzureError(&resp.Diagnostics, "Error while executing runCommand", err)
+	}
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
 
 	// Save data into Terraform state
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
@@ -196,10 +285,41 @@ func (r *InvokeResource) Read(ctx context.Context, req resource.ReadRequest, res
 		)
 	}
 
+	privateState, diags := req.Private.GetKey(ctx, privateStateKeyRunCommand)
+	resp.Diagnostics.Append(diags...)
+
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
+	// A runCommand execution is still in flight, resume polling its result.
+	if len(privateState) != 0 {
+		var state runCommandPrivateState
+
+		if err := json.Unmarshal(privateState, &state); err != nil {
+			addAzureError(&resp.Diagnostics, "Error while restoring runCommand poller", err)
+			return
+		}
+
+		poller, err := resumeRunCommand(ctx, r.data, data.ResourceGroupName.ValueString(), data.Name.ValueString(), state.ResumeToken)
+		if err != nil {
+			addAzureError(&resp.Diagnostics, "Error while restoring runCommand poller", err)
+			return
+		}
+
+		diags, err = pollRunCommand(ctx, poller, state.CommandID, data, resp.Private)
+		resp.Diagnostics.Append(diags...)
+
+		// The resource already exists, a failed command is recorded in provisioning_state instead of failing the refresh.
+		if err != nil {
+			resp.Diagnostics.AddWarning("runCommand failed", errorDetail(err))
+		}
+
+		if resp.Diagnostics.HasError() {
+			return
+		}
+	}
+
 	// Save updated data into Terraform state
 	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 }
@@ -207,13 +327,19 @@ func (r *InvokeResource) Read(ctx context.Context, req resource.ReadRequest, res
 func (r *InvokeResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
 	var data *InvokeModel
 
+	var plan *InvokeModel
+
 	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
 
 	if resp.Diagnostics.HasError() {
 		return
 	}
 
-	resp.Diagnostics.AddError("Unsupported operation", "azureakscommand_invoke does not support update")
+	// All other attributes force a new resource, only lock_group can be updated in-place.
+	data.LockGroup = plan.LockGroup
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 }
 
 func (r *InvokeResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
@@ -225,3 +351,55 @@ func (r *InvokeResource) Delete(ctx context.Context, req resource.DeleteRequest,
 		return
 	}
 }
+
+// pollRunCommand retrieves the result of a runCommand execution. With wait, it blocks until the command has finished,
+// otherwise the poller is queried once. Once the result is available, the poller is removed from the private state.
+// If polling gets interrupted, e.g. by a canceled context, a network error or a failed request, the command is
+// reported as pending with a warning.
+// A failed command is reported as error.
+func pollRunCommand(ctx context.Context, poller *runtime.Poller[armcontainerservice.ManagedClustersClientRunCommandResponse], commandID string, data *InvokeModel, private privateStateSetter) (diag.Diagnostics, error) {
+	var diags diag.Diagnostics
+
+	var err error
+
+	if data.Wait.IsNull() || data.Wait.ValueBool() {
+		_, err = poller.PollUntilDone(ctx, nil)
+	} else if !poller.Done() {
+		_, err = poller.Poll(ctx)
+	}
+
+	// Errors of a finished operation are returned by poller.Result. Any other error, including failed requests like
+	// 403 or 404, only interrupts polling.
+	if err != nil && !poller.Done() {
+		data.CommandResultModel = pendingCommandResult(commandID)
+
+		diags.AddWarning(
+			"Polling runCommand result interrupted",
+			fmt.Sprintf("Polling the result of command %q was interrupted: %s\n\nThe result will be retrieved on the next refresh instead of executing the command again.", commandID, errorDetail(err)),
+		)
+
+		return diags, nil
+	}
+
+	if !poller.Done() {
+		data.CommandResultModel = pendingCommandResult(commandID)
+
+		return diags, nil
+	}
+
+	diags.Append(private.SetKey(ctx, privateStateKeyRunCommand, nil)...)
+
+	runCommand, err := poller.Result(ctx)
+	if err != nil {
+		data.CommandResultModel = pendingCommandResult(commandID)
+		data.ProvisioningState = types.StringValue("Failed")
+		data.ProvisioningReason = types.StringValue(err.Error())
+
+		return diags, err
+	}
+
+	processRunCommand(&runCommand.RunCommandResult, &data.CommandResultModel)
+	diags.Append(commandResultWarnings(data.CommandResultModel)...)
+
+	return diags, nil
+}
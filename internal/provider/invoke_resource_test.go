@@ -0,0 +1,149 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+func testAccInvokeResourceConfig(cluster string, command string) string {
+	return fmt.Sprintf(`
+resource "azureakscommand_invoke" "test" {
+  resource_group_name = "rg"
+  name                = %q
+  command             = %q
+}
+`, cluster, command)
+}
+
+func TestAccInvokeResource(t *testing.T) {
+	tests := []struct {
+		name              string
+		cluster           string
+		command           string
+		output            string
+		exitCode          string
+		provisioningState string
+	}{
+		{name: "non-AAD cluster", cluster: "plain", command: "echo hello", output: "hello\n", exitCode: "0", provisioningState: "Succeeded"},
+		{name: "AAD cluster", cluster: "aad", command: "echo hello", output: "hello\n", exitCode: "0", provisioningState: "Succeeded"},
+		{name: "exit code", cluster: "plain", command: "exit 3", output: "failed\n", exitCode: "3", provisioningState: "Succeeded"},
+		{name: "long poll", cluster: "plain", command: "sleep", output: "done\n", exitCode: "0", provisioningState: "Succeeded"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resource.Test(t, resource.TestCase{
+				ProtoV6ProviderFactories: testAccProtoV6ProviderFactoriesWithFake(t, newFakeBackend()),
+				Steps: []resource.TestStep{
+					{
+						Config: testAccInvokeResourceConfig(tt.cluster, tt.command),
+						Check: resource.ComposeAggregateTestCheckFunc(
+							resource.TestCheckResourceAttrSet("azureakscommand_invoke.test", "id"),
+							resource.TestCheckResourceAttr("azureakscommand_invoke.test", "output", tt.output),
+							resource.TestCheckResourceAttr("azureakscommand_invoke.test", "exit_code", tt.exitCode),
+							resource.TestCheckResourceAttr("azureakscommand_invoke.test", "provisioning_state", tt.provisioningState),
+						),
+					},
+				},
+			})
+		})
+	}
+}
+
+func TestAccInvokeResourceErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		cluster string
+		command string
+		error   string
+	}{
+		{name: "failed command", cluster: "plain", command: "broken", error: "pod failed to start"},
+		{name: "rejected command", cluster: "plain", command: "busy", error: `ERROR CODE: Conflict`},
+		{name: "unknown cluster", cluster: "missing", command: "echo hello", error: "Managed Cluster not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resource.Test(t, resource.TestCase{
+				ProtoV6ProviderFactories: testAccProtoV6ProviderFactoriesWithFake(t, newFakeBackend()),
+				Steps: []resource.TestStep{
+					{
+						Config:      testAccInvokeResourceConfig(tt.cluster, tt.command),
+						ExpectError: regexp.MustCompile(tt.error),
+					},
+				},
+			})
+		})
+	}
+}
+
+func TestAccInvokeResourceWithoutWait(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactoriesWithFake(t, newFakeBackend()),
+		Steps: []resource.TestStep{
+			{
+				Config: `
+resource "azureakscommand_invoke" "test" {
+  resource_group_name = "rg"
+  name                = "plain"
+  command             = "sleep"
+  wait                = false
+}
+
+data "azureakscommand_command_result" "test" {
+  resource_group_name = "rg"
+  name                = "plain"
+  command_id          = azureakscommand_invoke.test.id
+}
+`,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttrSet("azureakscommand_invoke.test", "id"),
+					resource.TestCheckResourceAttrPair("data.azureakscommand_command_result.test", "id", "azureakscommand_invoke.test", "id"),
+				),
+			},
+		},
+	})
+}
+
+type fakePrivateState map[string][]byte
+
+func (s fakePrivateState) SetKey(_ context.Context, key string, value []byte) diag.Diagnostics {
+	s[key] = value
+	return nil
+}
+
+func TestPollRunCommandInterrupted(t *testing.T) {
+	backend := &FakeManagedClusters{
+		Handler: func(FakeRunCommandRequest) FakeCommandResult {
+			return FakeCommandResult{Polls: 2, PollStatusCode: 403, PollErrorCode: "AuthorizationFailed"}
+		},
+	}
+
+	client := newFakeClient(t, backend)
+	data := newFakeInvokeModel("rg", "aks", "sleep", true)
+
+	poller, commandID, err := beginRunCommand(context.Background(), client, "rg", "aks", "sleep", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A failed poll doesn't end the command, the result is retrieved on the next refresh.
+	diags, err := pollRunCommand(context.Background(), poller, commandID, data, fakePrivateState{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diags.WarningsCount() != 1 || !strings.Contains(diags.Warnings()[0].Detail(), "AuthorizationFailed") {
+		t.Errorf("expected a warning with the failed request, got %v", diags)
+	}
+
+	if data.ProvisioningState.ValueString() != "Running" || data.Id.ValueString() != commandID {
+		t.Errorf("expected the pending command %q, got %q (ID %q)", commandID, data.ProvisioningState.ValueString(), data.Id.ValueString())
+	}
+}
@@ -0,0 +1,678 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/int64planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/listplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/mapplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+const (
+	jobDefaultNamespace    = "default"
+	jobDefaultTimeout      = "1h"
+	jobDefaultPollInterval = "30s"
+	jobManifestFileName    = "job.json"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.Resource = &JobResource{}
+var _ resource.ResourceWithValidateConfig = &JobResource{}
+var _ resource.ResourceWithModifyPlan = &JobResource{}
+
+func NewJobResource() resource.Resource {
+	return &JobResource{}
+}
+
+// JobResource defines the resource implementation.
+type JobResource struct {
+	data AzureAksCommandClient
+}
+
+// JobModel describes the resource data model.
+type JobModel struct {
+	Id                 types.String `tfsdk:"id"`
+	Name               types.String `tfsdk:"name"`
+	ResourceGroupName  types.String `tfsdk:"resource_group_name"`
+	JobName            types.String `tfsdk:"job_name"`
+	Namespace          types.String `tfsdk:"namespace"`
+	Image              types.String `tfsdk:"image"`
+	Command            types.List   `tfsdk:"command"`
+	Args               types.List   `tfsdk:"args"`
+	Env                types.Map    `tfsdk:"env"`
+	ServiceAccountName types.String `tfsdk:"service_account_name"`
+	BackoffLimit       types.Int64  `tfsdk:"backoff_limit"`
+	Timeout            types.String `tfsdk:"timeout"`
+	PollInterval       types.String `tfsdk:"poll_interval"`
+	Triggers           types.Map    `tfsdk:"triggers"`
+	LockGroup          types.String `tfsdk:"lock_group"`
+	Output             types.String `tfsdk:"output"`
+	Status             types.String `tfsdk:"status"`
+	Succeeded          types.Int64  `tfsdk:"succeeded"`
+	Failed             types.Int64  `tfsdk:"failed"`
+	StartTime          types.String `tfsdk:"start_time"`
+	CompletionTime     types.String `tfsdk:"completion_time"`
+}
+
+// jobStatus is the subset of the Kubernetes batch/v1 JobStatus evaluated by the provider.
+type jobStatus struct {
+	Succeeded      int64  `json:"succeeded"`
+	Failed         int64  `json:"failed"`
+	StartTime      string `json:"startTime"`
+	CompletionTime string `json:"completionTime"`
+	Conditions     []struct {
+		Type   string `json:"type"`
+		Status string `json:"status"`
+	} `json:"conditions"`
+}
+
+func (r *JobResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_job"
+}
+
+func (r *JobResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	description := "A resource to run a Kubernetes Job inside AKS. Use this resource for long-running tasks like database migrations, " +
+		"which would exceed the timeout of a single runCommand execution." +
+		"\n\n" +
+		"The Job is submitted through runCommand and its completion is polled with repeated short runCommand executions. " +
+		"Once the Job is finished, the logs of its pods are stored in `output`. " +
+		"If the Job fails or doesn't finish within `timeout`, apply fails and the resource is tainted, so the Job is deleted and re-created by the next apply. " +
+		"The status of the Job is refreshed on every plan. If the Job was deleted from the cluster, it's run again by the next apply." +
+		"\n\n" +
+		"The `triggers` argument allows specifying an arbitrary set of values that, when changed, will cause the resource to be replaced."
+
+	resp.Schema = schema.Schema{
+		// This description is used by the documentation generator and the language server.
+		MarkdownDescription: description,
+		Attributes: map[string]schema.Attribute{
+			"name": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "The name of the Managed Kubernetes Cluster. Changing this forces a new resource to be created.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"resource_group_name": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "Specifies the Resource Group where the Managed Kubernetes Cluster exists. Changing this forces a new resource to be created.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"job_name": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "The name of the Kubernetes Job. Changing this forces a new resource to be created.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"namespace": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "The namespace of the Kubernetes Job. Defaults to `" + jobDefaultNamespace + "`. Changing this forces a new resource to be created.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"image": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "The container image of the Job. Changing this forces a new resource to be created.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"command": schema.ListAttribute{
+				Optional:            true,
+				MarkdownDescription: "The entrypoint of the container. Changing this forces a new resource to be created.",
+				ElementType:         types.StringType,
+				PlanModifiers: []planmodifier.List{
+					listplanmodifier.RequiresReplace(),
+				},
+			},
+			"args": schema.ListAttribute{
+				Optional:            true,
+				MarkdownDescription: "The arguments to the entrypoint. Changing this forces a new resource to be created.",
+				ElementType:         types.StringType,
+				PlanModifiers: []planmodifier.List{
+					listplanmodifier.RequiresReplace(),
+				},
+			},
+			"env": schema.MapAttribute{
+				Optional:            true,
+				MarkdownDescription: "Environment variables to set in the container. Changing this forces a new resource to be created.",
+				ElementType:         types.StringType,
+				Sensitive:           true,
+				PlanModifiers: []planmodifier.Map{
+					mapplanmodifier.RequiresReplace(),
+				},
+			},
+			"service_account_name": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "The name of the Kubernetes service account used to run the Job. Changing this forces a new resource to be created.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"backoff_limit": schema.Int64Attribute{
+				Optional:            true,
+				MarkdownDescription: "The number of retries before marking the Job as failed. Defaults to `0`. Changing this forces a new resource to be created.",
+				PlanModifiers: []planmodifier.Int64{
+					int64planmodifier.RequiresReplace(),
+				},
+			},
+			"timeout": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "The maximum duration to wait for the Job to finish, e.g. `30m` or `2h`. Defaults to `" + jobDefaultTimeout + "`.",
+			},
+			"poll_interval": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "The duration to wait between two status checks of the Job. Defaults to `" + jobDefaultPollInterval + "`.",
+			},
+			"lock_group": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "Commands of the same lock group are strictly serialized within a provider instance, e.g. commands touching the same component. The lock group is held from the submission of the Job until it has finished.",
+			},
+			"triggers": schema.MapAttribute{
+				Optional:            true,
+				MarkdownDescription: "A map of arbitrary strings that, when changed, will force the resource to be replaced, re-running the Job.",
+				ElementType:         types.StringType,
+				PlanModifiers: []planmodifier.Map{
+					mapplanmodifier.RequiresReplace(),
+				},
+			},
+			"id": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "The UID of the Kubernetes Job",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"output": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "The logs of all pods of the Job. Each line is prefixed with the name of the pod and container.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"status": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "The final condition of the Job. Either `Complete` or `Failed`.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"succeeded": schema.Int64Attribute{
+				Computed:            true,
+				MarkdownDescription: "The number of pods which reached phase Succeeded.",
+				PlanModifiers: []planmodifier.Int64{
+					int64planmodifier.UseStateForUnknown(),
+				},
+			},
+			"failed": schema.Int64Attribute{
+				Computed:            true,
+				MarkdownDescription: "The number of pods which reached phase Failed.",
+				PlanModifiers: []planmodifier.Int64{
+					int64planmodifier.UseStateForUnknown(),
+				},
+			},
+			"start_time": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "The time as RFC 3339 timestamp when the Job was started.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"completion_time": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "The time as RFC 3339 timestamp when the Job was completed.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+		},
+	}
+}
+
+func (r *JobResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var data JobModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	for _, attribute := range []struct {
+		name  string
+		value types.String
+	}{
+		{"timeout", data.Timeout},
+		{"poll_interval", data.PollInterval},
+	} {
+		if attribute.value.IsNull() || attribute.value.IsUnknown() {
+			continue
+		}
+
+		if _, err := time.ParseDuration(attribute.value.ValueString()); err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root(attribute.name), "Invalid duration", err.Error())
+		}
+	}
+}
+
+// ModifyPlan rejects Jobs, whose command line is denied by the command policy of the provider, at plan time.
+func (r *JobResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	// Nothing is executed on destroy.
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var data JobModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Unknown command lines are checked by Create before the Job is submitted.
+	if commandLine, ok := jobCommandLine(data); ok {
+		addCommandPolicyError(&resp.Diagnostics, path.Root("command"), r.data, commandLine)
+	}
+}
+
+func (r *JobResource) Configure(_ context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(AzureAksCommandClient)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected AzureAksCommandClient, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.data = data
+}
+
+func (r *JobResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data *JobModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	// Prevent panic if the provider has not been configured.
+	if r.data.managedClustersClient == nil || r.data.tokenCredential == nil {
+		resp.Diagnostics.AddError(
+			"Unconfigured Client",
+			"Expected configured client. Please report this issue to the provider developers.",
+		)
+	}
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// The policy applies to the container of the Job, not to the kubectl commands, which submit and watch it.
+	commandLine, _ := jobCommandLine(*data)
+	addCommandPolicyError(&resp.Diagnostics, path.Root("command"), r.data, commandLine)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// The lock group is held until the Job has finished, not only during the single commands.
+	ctx, releaseLockGroup, err := r.data.limiter.acquireLockGroup(contextWithLockGroup(ctx, data.LockGroup.ValueString()))
+	if err != nil {
+		resp.Diagnostics.AddError("Error while waiting for lock group", err.Error())
+		return
+	}
+	defer releaseLockGroup()
+
+	manifest, diags := buildJobManifest(ctx, data)
+	resp.Diagnostics.Append(diags...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	commandContext, err := buildCommandContext(map[string][]byte{jobManifestFileName: manifest})
+	if err != nil {
+		resp.Diagnostics.AddError("Error while building Job manifest", err.Error())
+		return
+	}
+
+	resourceGroupName := data.ResourceGroupName.ValueString()
+	name := data.Name.ValueString()
+	namespace := stringValueOrDefault(data.Namespace, jobDefaultNamespace)
+	jobName := data.JobName.ValueString()
+
+	uid, err := runKubectl(ctx, r.data, resourceGroupName, name, "kubectl apply -f "+jobManifestFileName+" -o "+shellQuote("jsonpath={.metadata.uid}"), commandContext)
+	if err != nil {
+		addAzureError(&resp.Diagnostics, "Error while submitting Job", err)
+		return
+	}
+
+	data.Id = types.StringValue(strings.TrimSpace(uid))
+	data.Output = types.StringNull()
+	data.Status = types.StringNull()
+	data.Succeeded = types.Int64Null()
+	data.Failed = types.Int64Null()
+	data.StartTime = types.StringNull()
+	data.CompletionTime = types.StringNull()
+
+	// Once the Job is submitted, the state is saved even if waiting for the Job fails. Terraform taints the resource
+	// in that case and Delete removes the Job, instead of the next apply failing on the immutable Job spec.
+	defer func() {
+		resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+	}()
+
+	timeout, _ := time.ParseDuration(stringValueOrDefault(data.Timeout, jobDefaultTimeout))
+	pollInterval, _ := time.ParseDuration(stringValueOrDefault(data.PollInterval, jobDefaultPollInterval))
+	deadline := time.Now().Add(timeout)
+
+	statusCommand := fmt.Sprintf("kubectl get job --namespace %s %s -o %s", shellQuote(namespace), shellQuote(jobName), shellQuote("jsonpath={.status}"))
+
+	var status jobStatus
+
+	for {
+		output, err := runKubectl(ctx, r.data, resourceGroupName, name, statusCommand, "")
+		if err != nil {
+			addAzureError(&resp.Diagnostics, "Error while retrieving Job status", err)
+			return
+		}
+
+		status = jobStatus{}
+		if output = strings.TrimSpace(output); output == "" {
+			// The status of a freshly created Job can be empty
+			output = "{}"
+		}
+
+		if err = json.Unmarshal([]byte(output), &status); err != nil {
+			resp.Diagnostics.AddError("Error while parsing Job status", fmt.Sprintf("%s: %s", err.Error(), output))
+			return
+		}
+
+		if status.finishedCondition() != "" {
+			break
+		}
+
+		if time.Now().Add(pollInterval).After(deadline) {
+			resp.Diagnostics.AddError(
+				"Timeout while waiting for Job",
+				fmt.Sprintf("Job %s/%s did not finish within %s.", namespace, jobName, timeout),
+			)
+
+			return
+		}
+
+		if err = sleepContext(ctx, pollInterval); err != nil {
+			addAzureError(&resp.Diagnostics, "Error while waiting for Job", err)
+			return
+		}
+	}
+
+	data.Status = types.StringValue(status.finishedCondition())
+	data.Succeeded = types.Int64Value(status.Succeeded)
+	data.Failed = types.Int64Value(status.Failed)
+	data.StartTime = stringValueOrNull(status.StartTime)
+	data.CompletionTime = stringValueOrNull(status.CompletionTime)
+
+	output, err := runKubectl(ctx, r.data, resourceGroupName, name, buildJobLogsCommand(data), "")
+	if err != nil {
+		addAzureError(&resp.Diagnostics, "Error while retrieving Job logs", err)
+		return
+	}
+
+	data.Output = types.StringValue(output)
+
+	if status.finishedCondition() == "Failed" {
+		resp.Diagnostics.AddError(
+			"Job failed",
+			fmt.Sprintf("Job %s/%s failed with %d failed pods.\n\nLogs:\n%s", namespace, jobName, status.Failed, output),
+		)
+	}
+}
+
+// Read refreshes the status of the Job. If the Job was deleted or replaced by a Job with another UID, the resource is
+// removed from the state, so the next apply runs the Job again. The output is kept, since the pods of a finished Job
+// may already be gone.
+func (r *JobResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data *JobModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	// Prevent panic if the provider has not been configured.
+	if r.data.managedClustersClient == nil || r.data.tokenCredential == nil {
+		resp.Diagnostics.AddError(
+			"Unconfigured Client",
+			"Expected configured client. Please report this issue to the provider developers.",
+		)
+	}
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	namespace := stringValueOrDefault(data.Namespace, jobDefaultNamespace)
+	getCommand := fmt.Sprintf("kubectl get job --namespace %s %s --ignore-not-found -o json", shellQuote(namespace), shellQuote(data.JobName.ValueString()))
+
+	output, err := runKubectl(contextWithLockGroup(ctx, data.LockGroup.ValueString()), r.data, data.ResourceGroupName.ValueString(), data.Name.ValueString(), getCommand, "")
+	if err != nil {
+		addAzureError(&resp.Diagnostics, "Error while retrieving Job", err)
+		return
+	}
+
+	var job struct {
+		Metadata struct {
+			Uid string `json:"uid"`
+		} `json:"metadata"`
+		Status jobStatus `json:"status"`
+	}
+
+	if output = strings.TrimSpace(output); output != "" {
+		if err = json.Unmarshal([]byte(output), &job); err != nil {
+			resp.Diagnostics.AddError("Error while parsing Job", fmt.Sprintf("%s: %s", err.Error(), output))
+			return
+		}
+	}
+
+	if job.Metadata.Uid == "" || job.Metadata.Uid != data.Id.ValueString() {
+		tflog.Info(ctx, "Job not found, removing it from state", map[string]interface{}{
+			"namespace": namespace,
+			"job_name":  data.JobName.ValueString(),
+			"uid":       data.Id.ValueString(),
+		})
+
+		resp.State.RemoveResource(ctx)
+
+		return
+	}
+
+	data.Status = stringValueOrNull(job.Status.finishedCondition())
+	data.Succeeded = types.Int64Value(job.Status.Succeeded)
+	data.Failed = types.Int64Value(job.Status.Failed)
+	data.StartTime = stringValueOrNull(job.Status.StartTime)
+	data.CompletionTime = stringValueOrNull(job.Status.CompletionTime)
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *JobResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var data *JobModel
+
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Only timeout, poll_interval and lock_group can be updated in-place, they are not used after the Job has finished.
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *JobResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data *JobModel
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	// Prevent panic if the provider has not been configured.
+	if r.data.managedClustersClient == nil || r.data.tokenCredential == nil {
+		resp.Diagnostics.AddError(
+			"Unconfigured Client",
+			"Expected configured client. Please report this issue to the provider developers.",
+		)
+	}
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	namespace := stringValueOrDefault(data.Namespace, jobDefaultNamespace)
+	deleteCommand := fmt.Sprintf("kubectl delete job --namespace %s %s --ignore-not-found", shellQuote(namespace), shellQuote(data.JobName.ValueString()))
+
+	if _, err := runKubectl(contextWithLockGroup(ctx, data.LockGroup.ValueString()), r.data, data.ResourceGroupName.ValueString(), data.Name.ValueString(), deleteCommand, ""); err != nil {
+		addAzureError(&resp.Diagnostics, "Error while deleting Job", err)
+	}
+}
+
+// finishedCondition returns the type of the condition, which marks the Job as finished. An empty string is returned,
+// if the Job is still running.
+func (s jobStatus) finishedCondition() string {
+	for _, condition := range s.Conditions {
+		if condition.Status == "True" && (condition.Type == "Complete" || condition.Type == "Failed") {
+			return condition.Type
+		}
+	}
+
+	return ""
+}
+
+// jobCommandLine returns the command line of the Job, which is checked by the command policy: the image followed by
+// the command and args of the container, separated by spaces. ok is false, if one of them is unknown.
+func jobCommandLine(data JobModel) (commandLine string, ok bool) {
+	if data.Image.IsUnknown() || data.Command.IsUnknown() || data.Args.IsUnknown() {
+		return "", false
+	}
+
+	parts := []string{data.Image.ValueString()}
+
+	for _, list := range []types.List{data.Command, data.Args} {
+		for _, element := range list.Elements() {
+			if v, isString := element.(types.String); isString && v.IsUnknown() {
+				return "", false
+			}
+		}
+
+		parts = append(parts, stringListValues(list)...)
+	}
+
+	return strings.Join(parts, " "), true
+}
+
+// buildJobLogsCommand returns the kubectl command which prints the logs of all pods of the Job. A Job creates a new
+// pod for every retry, each line is prefixed with the name of the pod and container.
+func buildJobLogsCommand(data *JobModel) string {
+	var backoffLimit int64
+	if !data.BackoffLimit.IsNull() {
+		backoffLimit = data.BackoffLimit.ValueInt64()
+	}
+
+	return fmt.Sprintf("kubectl logs --namespace %s --selector %s --all-containers --prefix --tail=-1 --max-log-requests=%d",
+		shellQuote(stringValueOrDefault(data.Namespace, jobDefaultNamespace)), shellQuote("job-name="+data.JobName.ValueString()), max(backoffLimit+1, 5))
+}
+
+// buildJobManifest renders the batch/v1 Job as JSON.
+func buildJobManifest(ctx context.Context, data *JobModel) ([]byte, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	container := map[string]any{
+		"name":  "job",
+		"image": data.Image.ValueString(),
+	}
+
+	for key, list := range map[string]types.List{"command": data.Command, "args": data.Args} {
+		if list.IsNull() {
+			continue
+		}
+
+		var values []string
+		diags.Append(list.ElementsAs(ctx, &values, false)...)
+		container[key] = values
+	}
+
+	if !data.Env.IsNull() {
+		var env map[string]string
+		diags.Append(data.Env.ElementsAs(ctx, &env, false)...)
+
+		names := make([]string, 0, len(env))
+		for name := range env {
+			names = append(names, name)
+		}
+
+		sort.Strings(names)
+
+		envVars := make([]map[string]string, 0, len(names))
+		for _, name := range names {
+			envVars = append(envVars, map[string]string{"name": name, "value": env[name]})
+		}
+
+		container["env"] = envVars
+	}
+
+	podSpec := map[string]any{
+		"restartPolicy": "Never",
+		"containers":    []any{container},
+	}
+
+	if !data.ServiceAccountName.IsNull() {
+		podSpec["serviceAccountName"] = data.ServiceAccountName.ValueString()
+	}
+
+	var backoffLimit int64
+	if !data.BackoffLimit.IsNull() {
+		backoffLimit = data.BackoffLimit.ValueInt64()
+	}
+
+	manifest, err := json.Marshal(map[string]any{
+		"apiVersion": "batch/v1",
+		"kind":       "Job",
+		"metadata": map[string]any{
+			"name":      data.JobName.ValueString(),
+			"namespace": stringValueOrDefault(data.Namespace, jobDefaultNamespace),
+			"labels": map[string]string{
+				"app.kubernetes.io/managed-by": "terraform-provider-azureakscommand",
+			},
+		},
+		"spec": map[string]any{
+			"backoffLimit": backoffLimit,
+			"template": map[string]any{
+				"spec": podSpec,
+			},
+		},
+	})
+	if err != nil {
+		diags.AddError("Error while building Job manifest", err.Error())
+	}
+
+	return manifest, diags
+}
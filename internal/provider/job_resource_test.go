@@ -0,0 +1,79 @@
+package provider
+
+import (
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+// newFakeJobBackend returns a backend, which answers the kubectl commands of azureakscommand_job for a Job with the
+// UID 5f3e1a2c. Once deleted is set, the Job is gone.
+func newFakeJobBackend(deleted *atomic.Bool) *FakeManagedClusters {
+	const status = `{"succeeded":1,"startTime":"2026-10-18T09:00:00Z","completionTime":"2026-10-18T09:01:00Z","conditions":[{"type":"Complete","status":"True"}]}`
+
+	return &FakeManagedClusters{
+		Clusters: map[string]FakeCluster{"rg/plain": {}},
+		Handler: func(request FakeRunCommandRequest) FakeCommandResult {
+			command := *request.Request.Command
+
+			switch {
+			case strings.HasPrefix(command, "kubectl apply"):
+				return FakeCommandResult{Logs: "5f3e1a2c"}
+			case strings.HasPrefix(command, "kubectl get job") && strings.Contains(command, "jsonpath={.status}"):
+				return FakeCommandResult{Logs: status}
+			case strings.HasPrefix(command, "kubectl get job") && strings.HasSuffix(command, "--ignore-not-found -o json"):
+				if deleted.Load() {
+					return FakeCommandResult{}
+				}
+
+				return FakeCommandResult{Logs: `{"metadata":{"name":"migrate","uid":"5f3e1a2c"},"status":` + status + `}`}
+			case strings.HasPrefix(command, "kubectl logs"):
+				return FakeCommandResult{Logs: "[pod/migrate-x7k2p/migrate] done\n"}
+			case strings.HasPrefix(command, "kubectl delete job"):
+				return FakeCommandResult{}
+			}
+
+			return FakeCommandResult{ExitCode: 1, Logs: "unexpected command: " + command}
+		},
+	}
+}
+
+func TestAccJobResource(t *testing.T) {
+	var deleted atomic.Bool
+
+	const config = `
+resource "azureakscommand_job" "test" {
+  resource_group_name = "rg"
+  name                = "plain"
+  job_name            = "migrate"
+  image               = "busybox:1.36"
+  command             = ["echo", "done"]
+  poll_interval       = "1ms"
+}
+`
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactoriesWithFake(t, newFakeJobBackend(&deleted)),
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("azureakscommand_job.test", "id", "5f3e1a2c"),
+					resource.TestCheckResourceAttr("azureakscommand_job.test", "status", "Complete"),
+					resource.TestCheckResourceAttr("azureakscommand_job.test", "succeeded", "1"),
+					resource.TestCheckResourceAttr("azureakscommand_job.test", "completion_time", "2026-10-18T09:01:00Z"),
+					resource.TestCheckResourceAttr("azureakscommand_job.test", "output", "[pod/migrate-x7k2p/migrate] done\n"),
+				),
+			},
+			{
+				// The Job was deleted from the cluster, so it's planned to run again.
+				PreConfig:          func() { deleted.Store(true) },
+				Config:             config,
+				PlanOnly:           true,
+				ExpectNonEmptyPlan: true,
+			},
+		},
+	})
+}
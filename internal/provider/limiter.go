@@ -0,0 +1,121 @@
+package provider
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+// lockGroupContextKey is the context key of the lock group of a command.
+type lockGroupContextKey struct{}
+
+// contextWithLockGroup returns a context, which serializes all commands started with it against other commands of
+// the same lock group.
+func contextWithLockGroup(ctx context.Context, lockGroup string) context.Context {
+	if lockGroup == "" {
+		return ctx
+	}
+
+	return context.WithValue(ctx, lockGroupContextKey{}, lockGroup)
+}
+
+// commandLimiter limits the number of concurrent runCommand executions of a provider instance. The limits are
+// implemented as semaphores, which are shared by all resources and data sources through AzureAksCommandClient.
+type commandLimiter struct {
+	global     chan struct{}
+	perCluster int
+
+	mu         sync.Mutex
+	clusters   map[string]chan struct{}
+	lockGroups map[string]chan struct{}
+}
+
+// newCommandLimiter returns a limiter allowing maxConcurrent commands in total and maxPerCluster commands per
+// cluster. Values less than one disable the respective limit.
+func newCommandLimiter(maxConcurrent int, maxPerCluster int) *commandLimiter {
+	limiter := &commandLimiter{
+		perCluster: maxPerCluster,
+		clusters:   map[string]chan struct{}{},
+		lockGroups: map[string]chan struct{}{},
+	}
+
+	if maxConcurrent > 0 {
+		limiter.global = make(chan struct{}, maxConcurrent)
+	}
+
+	return limiter
+}
+
+// acquire blocks until the command may run on the cluster. The semaphores are always acquired in the order lock
+// group, cluster, global, which prevents deadlocks between commands. The returned function releases them.
+func (l *commandLimiter) acquire(ctx context.Context, clusterID string) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+
+	var semaphores []chan struct{}
+
+	if lockGroup, ok := ctx.Value(lockGroupContextKey{}).(string); ok {
+		semaphores = append(semaphores, l.semaphore(l.lockGroups, lockGroup, 1))
+	}
+
+	if l.perCluster > 0 {
+		semaphores = append(semaphores, l.semaphore(l.clusters, strings.ToLower(clusterID), l.perCluster))
+	}
+
+	if l.global != nil {
+		semaphores = append(semaphores, l.global)
+	}
+
+	release := func(acquired []chan struct{}) {
+		for i := len(acquired) - 1; i >= 0; i-- {
+			<-acquired[i]
+		}
+	}
+
+	for i, semaphore := range semaphores {
+		select {
+		case semaphore <- struct{}{}:
+		case <-ctx.Done():
+			release(semaphores[:i])
+
+			return nil, ctx.Err()
+		}
+	}
+
+	return func() { release(semaphores) }, nil
+}
+
+// acquireLockGroup blocks until no other command of the lock group of ctx is running and holds the lock group until
+// the returned function is called. It serializes operations consisting of several commands, e.g. a Job from its
+// submission until it has finished. The returned context doesn't carry the lock group anymore, so the commands of
+// the operation only wait for the other limits.
+func (l *commandLimiter) acquireLockGroup(ctx context.Context) (context.Context, func(), error) {
+	lockGroup, ok := ctx.Value(lockGroupContextKey{}).(string)
+	if l == nil || !ok {
+		return ctx, func() {}, nil
+	}
+
+	semaphore := l.semaphore(l.lockGroups, lockGroup, 1)
+
+	select {
+	case semaphore <- struct{}{}:
+	case <-ctx.Done():
+		return ctx, nil, ctx.Err()
+	}
+
+	return context.WithValue(ctx, lockGroupContextKey{}, nil), func() { <-semaphore }, nil
+}
+
+func (l *commandLimiter) semaphore(semaphores map[string]chan struct{}, key string, size int) chan struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	semaphore, ok := semaphores[key]
+	if !ok {
+		semaphore = make(chan struct{}, size)
+		semaphores[key] = semaphore
+	}
+
+	return semaphore
+}
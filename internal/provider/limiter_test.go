@@ -0,0 +1,43 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCommandLimiterAcquireLockGroup(t *testing.T) {
+	limiter := newCommandLimiter(0, 0)
+	ctx := contextWithLockGroup(context.Background(), "migrations")
+
+	jobCtx, release, err := limiter.acquireLockGroup(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The commands of the operation holding the lock group don't wait for it.
+	releaseCommand, err := limiter.acquire(jobCtx, "rg/aks")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	releaseCommand()
+
+	// Other commands of the lock group wait until the operation has finished.
+	timeoutCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+
+	if _, err := limiter.acquire(timeoutCtx, "rg/aks"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the lock group to be held, got %v", err)
+	}
+
+	release()
+
+	releaseCommand, err = limiter.acquire(ctx, "rg/aks")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	releaseCommand()
+}
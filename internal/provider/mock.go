@@ -0,0 +1,227 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/containerservice/armcontainerservice/v9"
+
+	"github.com/jkroepke/terraform-provider-azureakscommand/internal/clients"
+)
+
+// mockResponse is an entry of the mock_responses file. The first entry, whose regular expressions match the
+// cluster ("<resource_group_name>/<name>") and the command, answers the runCommand execution.
+type mockResponse struct {
+	Cluster           string `json:"cluster"`
+	Command           string `json:"command"`
+	ExitCode          int32  `json:"exit_code"`
+	Output            string `json:"output"`
+	ProvisioningState string `json:"provisioning_state"`
+	Reason            string `json:"reason"`
+
+	cluster *regexp.Regexp
+	command *regexp.Regexp
+}
+
+// loadMockResponses reads and validates the mock_responses file.
+func loadMockResponses(path string) ([]mockResponse, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading mock responses: %w", err)
+	}
+
+	var responses []mockResponse
+	if err := json.Unmarshal(content, &responses); err != nil {
+		return nil, fmt.Errorf("parsing mock responses %q: %w", path, err)
+	}
+
+	for i := range responses {
+		if responses[i].cluster, err = regexp.Compile(responses[i].Cluster); err != nil {
+			return nil, fmt.Errorf("parsing cluster of mock response %d: %w", i, err)
+		}
+
+		if responses[i].command, err = regexp.Compile(responses[i].Command); err != nil {
+			return nil, fmt.Errorf("parsing command of mock response %d: %w", i, err)
+		}
+	}
+
+	return responses, nil
+}
+
+// mockManagedClustersClient answers the calls of the provider from the mock responses without calling Azure. A
+// cluster exists, if the cluster expression of any mock response matches it. runCommand executions finish
+// immediately, their results are kept for GetCommandResult.
+type mockManagedClustersClient struct {
+	responses []mockResponse
+
+	mu      sync.Mutex
+	results map[string]armcontainerservice.RunCommandResult
+}
+
+var _ clients.ManagedClustersAPI = (*mockManagedClustersClient)(nil)
+
+// newMockManagedClustersClient returns a client, which answers the calls of the provider from the mock responses.
+// Commands without matching response fail.
+func newMockManagedClustersClient(responses []mockResponse) *mockManagedClustersClient {
+	return &mockManagedClustersClient{
+		responses: responses,
+		results:   map[string]armcontainerservice.RunCommandResult{},
+	}
+}
+
+func (c *mockManagedClustersClient) Get(_ context.Context, resourceGroupName string, resourceName string, _ *armcontainerservice.ManagedClustersClientGetOptions) (armcontainerservice.ManagedClustersClientGetResponse, error) {
+	cluster := resourceGroupName + "/" + resourceName
+
+	for _, response := range c.responses {
+		if response.cluster.MatchString(cluster) {
+			return armcontainerservice.ManagedClustersClientGetResponse{
+				ManagedCluster: armcontainerservice.ManagedCluster{
+					ID:         to.Ptr(mockClusterId(resourceGroupName, resourceName)),
+					Name:       to.Ptr(resourceName),
+					Properties: &armcontainerservice.ManagedClusterProperties{},
+				},
+			}, nil
+		}
+	}
+
+	return armcontainerservice.ManagedClustersClientGetResponse{}, mockResponseError(mockClusterId(resourceGroupName, resourceName), http.StatusNotFound,
+		"ResourceNotFound", fmt.Sprintf("no mock response matches cluster %q", cluster))
+}
+
+func (c *mockManagedClustersClient) BeginRunCommand(_ context.Context, resourceGroupName string, resourceName string, requestPayload armcontainerservice.RunCommandRequest, options *armcontainerservice.ManagedClustersClientBeginRunCommandOptions) (*runtime.Poller[armcontainerservice.ManagedClustersClientRunCommandResponse], error) {
+	if options != nil && options.ResumeToken != "" {
+		return nil, errors.New("resuming runCommand executions is not supported by mock responses")
+	}
+
+	cluster := resourceGroupName + "/" + resourceName
+
+	command := ""
+	if requestPayload.Command != nil {
+		command = *requestPayload.Command
+	}
+
+	for _, response := range c.responses {
+		if !response.cluster.MatchString(cluster) || !response.command.MatchString(command) {
+			continue
+		}
+
+		provisioningState := response.ProvisioningState
+		if provisioningState == "" {
+			provisioningState = "Succeeded"
+		}
+
+		now := time.Now()
+
+		c.mu.Lock()
+		commandId := fmt.Sprintf("%032x", len(c.results)+1)
+		result := armcontainerservice.RunCommandResult{
+			ID: to.Ptr(commandId),
+			Properties: &armcontainerservice.CommandResultProperties{
+				ExitCode:          to.Ptr(response.ExitCode),
+				Logs:              to.Ptr(response.Output),
+				ProvisioningState: to.Ptr(provisioningState),
+				Reason:            to.Ptr(response.Reason),
+				StartedAt:         to.Ptr(now),
+				FinishedAt:        to.Ptr(now),
+			},
+		}
+		c.results[commandId] = result
+		c.mu.Unlock()
+
+		handler := &mockRunCommandHandler{
+			commandResultUrl: mockClusterId(resourceGroupName, resourceName) + "/commandResults/" + commandId,
+			result:           result,
+		}
+
+		return runtime.NewPoller(handler.response(), runtime.Pipeline{}, &runtime.NewPollerOptions[armcontainerservice.ManagedClustersClientRunCommandResponse]{
+			Handler: handler,
+		})
+	}
+
+	return nil, fmt.Errorf("no mock response matches command %q on cluster %q", command, cluster)
+}
+
+func (c *mockManagedClustersClient) GetCommandResult(_ context.Context, resourceGroupName string, resourceName string, commandID string, _ *armcontainerservice.ManagedClustersClientGetCommandResultOptions) (armcontainerservice.ManagedClustersClientGetCommandResultResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	result, ok := c.results[commandID]
+	if !ok {
+		return armcontainerservice.ManagedClustersClientGetCommandResultResponse{}, mockResponseError(mockClusterId(resourceGroupName, resourceName)+"/commandResults/"+commandID, http.StatusNotFound,
+			"NotFound", fmt.Sprintf("command %q was not executed with mock responses", commandID))
+	}
+
+	return armcontainerservice.ManagedClustersClientGetCommandResultResponse{RunCommandResult: result}, nil
+}
+
+// mockRunCommandHandler is the runtime.PollingHandler of a runCommand execution answered by mock responses. Like
+// in ARM, the operation fails, if the provisioning state of the command is Failed.
+type mockRunCommandHandler struct {
+	commandResultUrl string
+	result           armcontainerservice.RunCommandResult
+}
+
+func (h *mockRunCommandHandler) Done() bool {
+	return true
+}
+
+func (h *mockRunCommandHandler) Poll(_ context.Context) (*http.Response, error) {
+	return h.response(), nil
+}
+
+func (h *mockRunCommandHandler) Result(_ context.Context, out *armcontainerservice.ManagedClustersClientRunCommandResponse) error {
+	if h.result.Properties != nil && h.result.Properties.ProvisioningState != nil && *h.result.Properties.ProvisioningState == "Failed" {
+		return runtime.NewResponseError(h.response())
+	}
+
+	out.RunCommandResult = h.result
+
+	return nil
+}
+
+// response returns the command result as a response of the commandResults endpoint.
+func (h *mockRunCommandHandler) response() *http.Response {
+	body, _ := json.Marshal(h.result)
+
+	return newMockResponse(h.commandResultUrl, http.StatusOK, string(body))
+}
+
+// mockClusterId returns the resource ID of a cluster answered by mock responses.
+func mockClusterId(resourceGroupName string, resourceName string) string {
+	return fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.ContainerService/managedClusters/%s", clients.FakeSubscriptionId, resourceGroupName, resourceName)
+}
+
+// mockResponseError returns the *azcore.ResponseError of a GET request of the resource, which failed like ARM.
+func mockResponseError(resourceId string, statusCode int, code string, message string) error {
+	body, _ := json.Marshal(map[string]map[string]string{
+		"error": {"code": code, "message": message},
+	})
+
+	return runtime.NewResponseError(newMockResponse(resourceId, statusCode, string(body)))
+}
+
+// newMockResponse returns the response of a GET request of the resource.
+func newMockResponse(resourceId string, statusCode int, body string) *http.Response {
+	return &http.Response{
+		Status:     fmt.Sprintf("%d %s", statusCode, http.StatusText(statusCode)),
+		StatusCode: statusCode,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request: &http.Request{
+			Method: http.MethodGet,
+			URL:    &url.URL{Scheme: "https", Host: "management.azure.com", Path: resourceId},
+		},
+	}
+}
@@ -0,0 +1,76 @@
+package provider
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMockResponses(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mock.json")
+
+	err := os.WriteFile(path, []byte(`[
+		{"cluster": "^rg/aks$", "command": "^kubectl get ns", "output": "default\n"},
+		{"cluster": "^rg/aks$", "command": "^broken$", "provisioning_state": "Failed", "reason": "pod failed to start"},
+		{"cluster": "^(rg|other)/", "command": "^exit 2$", "exit_code": 2}
+	]`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	responses, err := loadMockResponses(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := AzureAksCommandClient{
+		limiter:               newCommandLimiter(0, 0),
+		cache:                 newClusterCache(),
+		managedClustersClient: newMockManagedClustersClient(responses),
+	}
+
+	data := newFakeInvokeModel("rg", "aks", "kubectl get ns -o name", true)
+	if err := invokeCommand(context.Background(), client, data); err != nil {
+		t.Fatal(err)
+	}
+
+	if data.Output.ValueString() != "default\n" || data.ExitCode.ValueInt64() != 0 {
+		t.Errorf("unexpected result: output %q, exit_code %d", data.Output.ValueString(), data.ExitCode.ValueInt64())
+	}
+
+	data = newFakeInvokeModel("other", "aks", "exit 2", true)
+	if err := invokeCommand(context.Background(), client, data); err != nil {
+		t.Fatal(err)
+	}
+
+	if data.ExitCode.ValueInt64() != 2 {
+		t.Errorf("exit_code = %d, want 2", data.ExitCode.ValueInt64())
+	}
+
+	res, err := client.managedClustersClient.GetCommandResult(context.Background(), "other", "aks", data.Id.ValueString(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Properties == nil || res.Properties.ExitCode == nil || *res.Properties.ExitCode != 2 {
+		t.Errorf("unexpected command result: %+v", res.Properties)
+	}
+
+	data = newFakeInvokeModel("other", "aks", "kubectl get ns", true)
+	if err := invokeCommand(context.Background(), client, data); err == nil {
+		t.Error("expected an error for a command without mock response")
+	}
+
+	data = newFakeInvokeModel("rg", "aks", "broken", true)
+	if err := invokeCommand(context.Background(), client, data); err == nil || !strings.Contains(err.Error(), "pod failed to start") {
+		t.Errorf("expected the failed command to fail, got %v", err)
+	}
+
+	data = newFakeInvokeModel("unknown", "aks", "exit 2", true)
+	err = invokeCommand(context.Background(), client, data)
+	if summary, _ := describeAzureError("", err); summary != "Managed Cluster not found" {
+		t.Errorf("expected ResourceNotFound for a cluster without mock responses, got %v", err)
+	}
+}
@@ -0,0 +1,238 @@
+package provider
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+const (
+	nodeCommandDefaultImage       = "mcr.microsoft.com/cbl-mariner/busybox:2.0"
+	nodeCommandDefaultNamespace   = "default"
+	nodeCommandDefaultNodeTimeout = "5m"
+	nodeCommandResultPrefix       = "::azureakscommand::"
+)
+
+// nodeCommandScript launches a privileged debug pod on every selected node, waits for the commands to finish and
+// prints one result line per node. The debug pods run in parallel, so the runtime is bound by the node timeout
+// instead of growing with the number of nodes. The debug pods are removed on exit.
+const nodeCommandScript = `#!/bin/sh
+set -u
+
+NAMESPACE="$1"
+IMAGE="$2"
+NODE_TIMEOUT="$3"
+SELECTOR="$4"
+COMMAND="$(cat command.sh)"
+PODS=""
+STARTED=""
+
+cleanup() {
+  for pod in $PODS; do
+    kubectl delete pod --namespace "$NAMESPACE" "$pod" --ignore-not-found --wait=false >/dev/null 2>&1
+  done
+}
+
+trap cleanup EXIT
+
+NODES="$(kubectl get nodes ${SELECTOR:+--selector "$SELECTOR"} -o jsonpath='{.items[*].metadata.name}')" || exit 1
+
+for node in $NODES; do
+  pod="$(kubectl debug "node/$node" --namespace "$NAMESPACE" --image "$IMAGE" --profile sysadmin -- chroot /host /bin/sh -c "$COMMAND" 2>&1 | sed -n 's/^Creating debugging pod \([^ ]*\) .*/\1/p')"
+
+  if [ -z "$pod" ]; then
+    echo "` + nodeCommandResultPrefix + `$node::-1::$(echo "failed to create debug pod" | base64 | tr -d '\n')"
+    continue
+  fi
+
+  PODS="$PODS $pod"
+  STARTED="$STARTED $node=$pod"
+done
+
+DEADLINE=$(($(date +%s) + NODE_TIMEOUT))
+
+while [ -n "$PODS" ] && [ "$(date +%s)" -lt "$DEADLINE" ]; do
+  # shellcheck disable=SC2086
+  if phases="$(kubectl get pod --namespace "$NAMESPACE" $PODS --no-headers -o custom-columns=PHASE:.status.phase)"; then
+    echo "$phases" | grep -qvE '^(Succeeded|Failed)$' || break
+  fi
+
+  sleep 1
+done
+
+for entry in $STARTED; do
+  node="${entry%%=*}"
+  pod="${entry#*=}"
+
+  exit_code="$(kubectl get pod --namespace "$NAMESPACE" "$pod" -o jsonpath='{.status.containerStatuses[0].state.terminated.exitCode}')"
+  logs="$(kubectl logs --namespace "$NAMESPACE" "$pod" 2>&1 | base64 | tr -d '\n')"
+
+  echo "` + nodeCommandResultPrefix + `$node::${exit_code:--1}::$logs"
+done
+`
+
+// NodeCommandModel describes the resource and data source data model.
+type NodeCommandModel struct {
+	Id                types.String `tfsdk:"id"`
+	Name              types.String `tfsdk:"name"`
+	ResourceGroupName types.String `tfsdk:"resource_group_name"`
+	Command           types.String `tfsdk:"command"`
+	NodePool          types.String `tfsdk:"node_pool"`
+	NodeSelector      types.String `tfsdk:"node_selector"`
+	Image             types.String `tfsdk:"image"`
+	Namespace         types.String `tfsdk:"namespace"`
+	NodeTimeout       types.String `tfsdk:"node_timeout"`
+	Triggers          types.Map    `tfsdk:"triggers"`
+	LockGroup         types.String `tfsdk:"lock_group"`
+	Results           types.Map    `tfsdk:"results"`
+}
+
+// NodeCommandResultModel describes the result of the command on a single node.
+type NodeCommandResultModel struct {
+	ExitCode types.Int64  `tfsdk:"exit_code"`
+	Output   types.String `tfsdk:"output"`
+}
+
+var nodeCommandResultType = types.ObjectType{
+	AttrTypes: map[string]attr.Type{
+		"exit_code": types.Int64Type,
+		"output":    types.StringType,
+	},
+}
+
+func runNodeCommand(ctx context.Context, client AzureAksCommandClient, data *NodeCommandModel) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	// The policy applies to the command of the user, not to nodeCommandScript, which wraps it.
+	addCommandPolicyError(&diags, path.Root("command"), client, data.Command.ValueString())
+
+	if diags.HasError() {
+		return diags
+	}
+
+	nodeTimeout, err := time.ParseDuration(stringValueOrDefault(data.NodeTimeout, nodeCommandDefaultNodeTimeout))
+	if err != nil {
+		diags.AddError("Invalid node_timeout", err.Error())
+		return diags
+	}
+
+	selector := data.NodeSelector.ValueString()
+	if !data.NodePool.IsNull() {
+		selector = "agentpool=" + data.NodePool.ValueString()
+	}
+
+	commandContext, err := buildCommandContext(map[string][]byte{
+		"node-command.sh": []byte(nodeCommandScript),
+		"command.sh":      []byte(data.Command.ValueString()),
+	})
+	if err != nil {
+		diags.AddError("Error while building command context", err.Error())
+		return diags
+	}
+
+	command := strings.Join([]string{
+		"sh", "node-command.sh",
+		shellQuote(stringValueOrDefault(data.Namespace, nodeCommandDefaultNamespace)),
+		shellQuote(stringValueOrDefault(data.Image, nodeCommandDefaultImage)),
+		strconv.Itoa(int(nodeTimeout.Seconds())),
+		shellQuote(selector),
+	}, " ")
+
+	res, err := runCommand(contextWithLockGroup(ctx, data.LockGroup.ValueString()), client, data.ResourceGroupName.ValueString(), data.Name.ValueString(), command, commandContext)
+	if err != nil {
+		addAzureError(&diags, "Error while executing runCommand", err)
+		return diags
+	}
+
+	var logs string
+	if res.Properties != nil && res.Properties.Logs != nil {
+		logs = *res.Properties.Logs
+	}
+
+	if res.Properties == nil || res.Properties.ExitCode == nil || *res.Properties.ExitCode != 0 {
+		diags.AddError("Error while executing node command", logs)
+		return diags
+	}
+
+	if res.ID != nil {
+		data.Id = types.StringValue(*res.ID)
+	} else {
+		data.Id = types.StringNull()
+	}
+
+	results, err := parseNodeCommandResults(logs)
+	if err != nil {
+		diags.AddError("Error while parsing node command results", err.Error())
+		return diags
+	}
+
+	var d diag.Diagnostics
+
+	data.Results, d = types.MapValueFrom(ctx, nodeCommandResultType, results)
+	diags.Append(d...)
+
+	return diags
+}
+
+// parseNodeCommandResults extracts the per-node results from the output of nodeCommandScript.
+func parseNodeCommandResults(logs string) (map[string]NodeCommandResultModel, error) {
+	results := map[string]NodeCommandResultModel{}
+
+	scanner := bufio.NewScanner(strings.NewReader(logs))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(logs)+1)
+
+	for scanner.Scan() {
+		line, found := strings.CutPrefix(strings.TrimSpace(scanner.Text()), nodeCommandResultPrefix)
+		if !found {
+			continue
+		}
+
+		parts := strings.SplitN(line, "::", 3)
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("unexpected result line: %s", line)
+		}
+
+		result := NodeCommandResultModel{
+			ExitCode: types.Int64Null(),
+		}
+
+		if exitCode, err := strconv.ParseInt(parts[1], 10, 64); err == nil && exitCode >= 0 {
+			result.ExitCode = types.Int64Value(exitCode)
+		}
+
+		output, err := base64.StdEncoding.DecodeString(parts[2])
+		if err != nil {
+			return nil, fmt.Errorf("decoding output of node %s: %w", parts[0], err)
+		}
+
+		result.Output = types.StringValue(string(output))
+		results[parts[0]] = result
+	}
+
+	return results, scanner.Err()
+}
+
+func validateNodeCommandConfig(data NodeCommandModel) diag.Diagnostics {
+	var diags diag.Diagnostics
+
+	if !data.NodePool.IsNull() && !data.NodeSelector.IsNull() {
+		diags.AddAttributeError(path.Root("node_selector"), "Conflicting node selection", "Only one of node_pool or node_selector can be configured.")
+	}
+
+	if !data.NodeTimeout.IsNull() && !data.NodeTimeout.IsUnknown() {
+		if _, err := time.ParseDuration(data.NodeTimeout.ValueString()); err != nil {
+			diags.AddAttributeError(path.Root("node_timeout"), "Invalid duration", err.Error())
+		}
+	}
+
+	return diags
+}
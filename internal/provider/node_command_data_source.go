@@ -0,0 +1,158 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ datasource.DataSource = &NodeCommandDataSource{}
+var _ datasource.DataSourceWithValidateConfig = &NodeCommandDataSource{}
+
+func NewNodeCommandDataSource() datasource.DataSource {
+	return &NodeCommandDataSource{}
+}
+
+// NodeCommandDataSource defines the data source implementation.
+type NodeCommandDataSource struct {
+	data AzureAksCommandClient
+}
+
+func (d *NodeCommandDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_node_command"
+}
+
+func (d *NodeCommandDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "A data source to run a command on the nodes of a AKS. The command is executed through privileged debug pods " +
+			"(`kubectl debug node/...`) with the root filesystem of the node available via `chroot /host`. The debug pods are removed afterwards. " +
+			"It's recommended to use `azureakscommand_node_command` data source to perform readonly action, like sysctl checks or inspecting the containerd configuration.",
+		Attributes: map[string]schema.Attribute{
+			"name": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "The name of the Managed Kubernetes Cluster.",
+			},
+			"resource_group_name": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "Specifies the Resource Group where the Managed Kubernetes Cluster exists.",
+			},
+			"command": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "The command to run on each node.",
+			},
+			"node_pool": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "Run the command on all nodes of this node pool. Conflicts with `node_selector`.",
+			},
+			"node_selector": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "Run the command on all nodes matching this label selector. Conflicts with `node_pool`. If neither is set, the command runs on all nodes.",
+			},
+			"image": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "The container image of the debug pods. Defaults to `" + nodeCommandDefaultImage + "`.",
+			},
+			"namespace": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "The namespace of the debug pods. Defaults to `" + nodeCommandDefaultNamespace + "`.",
+			},
+			"node_timeout": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "The maximum duration to wait for the command. The command runs on all selected nodes in parallel, so this also bounds the total runtime. Defaults to `" + nodeCommandDefaultNodeTimeout + "`.",
+			},
+			"lock_group": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "Commands of the same lock group are strictly serialized within a provider instance, e.g. commands touching the same component.",
+			},
+			"triggers": schema.MapAttribute{
+				Optional:            true,
+				MarkdownDescription: "A map of arbitrary strings that, when changed, will re-run the command.",
+				ElementType:         types.StringType,
+			},
+			"id": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "The runCommand id",
+			},
+			"results": schema.MapNestedAttribute{
+				Computed:            true,
+				MarkdownDescription: "The results of the command, keyed by node name.",
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"exit_code": schema.Int64Attribute{
+							Computed:            true,
+							MarkdownDescription: "The exit code of the command on the node",
+						},
+						"output": schema.StringAttribute{
+							Computed:            true,
+							MarkdownDescription: "The output of the command on the node",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (d *NodeCommandDataSource) ValidateConfig(ctx context.Context, req datasource.ValidateConfigRequest, resp *datasource.ValidateConfigResponse) {
+	var data NodeCommandModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(validateNodeCommandConfig(data)...)
+}
+
+func (d *NodeCommandDataSource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(AzureAksCommandClient)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected AzureAksCommandClient, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	d.data = data
+}
+
+func (d *NodeCommandDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data *NodeCommandModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	// Prevent panic if the provider has not been configured.
+	if d.data.managedClustersClient == nil || d.data.tokenCredential == nil {
+		resp.Diagnostics.AddError(
+			"Unconfigured Client",
+			"Expected configured client. Please report this issue to the provider developers.",
+		)
+	}
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(runNodeCommand(ctx, d.data, data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
@@ -0,0 +1,261 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/mapplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ resource.Resource = &NodeCommandResource{}
+var _ resource.ResourceWithValidateConfig = &NodeCommandResource{}
+var _ resource.ResourceWithModifyPlan = &NodeCommandResource{}
+
+func NewNodeCommandResource() resource.Resource {
+	return &NodeCommandResource{}
+}
+
+// NodeCommandResource defines the resource implementation.
+type NodeCommandResource struct {
+	data AzureAksCommandClient
+}
+
+func (r *NodeCommandResource) Metadata(_ context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_node_command"
+}
+
+func (r *NodeCommandResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+	description := "A resource to run a command on the nodes of a AKS. The command is executed through privileged debug pods " +
+		"(`kubectl debug node/...`) with the root filesystem of the node available via `chroot /host`. The debug pods are removed afterwards." +
+		"\n\n" +
+		"The `triggers` argument allows specifying an arbitrary set of values that, when changed, will cause the resource to be replaced."
+
+	resp.Schema = schema.Schema{
+		// This description is used by the documentation generator and the language server.
+		MarkdownDescription: description,
+		Attributes: map[string]schema.Attribute{
+			"name": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "The name of the Managed Kubernetes Cluster. Changing this forces a new resource to be created.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"resource_group_name": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "Specifies the Resource Group where the Managed Kubernetes Cluster exists. Changing this forces a new resource to be created.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"command": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "The command to run on each node. Changing this forces a new resource to be created.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"node_pool": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "Run the command on all nodes of this node pool. Conflicts with `node_selector`. Changing this forces a new resource to be created.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"node_selector": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "Run the command on all nodes matching this label selector. Conflicts with `node_pool`. If neither is set, the command runs on all nodes. Changing this forces a new resource to be created.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"image": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "The container image of the debug pods. Defaults to `" + nodeCommandDefaultImage + "`. Changing this forces a new resource to be created.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"namespace": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "The namespace of the debug pods. Defaults to `" + nodeCommandDefaultNamespace + "`. Changing this forces a new resource to be created.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"node_timeout": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "The maximum duration to wait for the command. The command runs on all selected nodes in parallel, so this also bounds the total runtime. Defaults to `" + nodeCommandDefaultNodeTimeout + "`. Changing this forces a new resource to be created.",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"lock_group": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "Commands of the same lock group are strictly serialized within a provider instance, e.g. commands touching the same component.",
+			},
+			"triggers": schema.MapAttribute{
+				Optional:            true,
+				MarkdownDescription: "A map of arbitrary strings that, when changed, will force the resource to be replaced, re-running the command.",
+				ElementType:         types.StringType,
+				PlanModifiers: []planmodifier.Map{
+					mapplanmodifier.RequiresReplace(),
+				},
+			},
+			"id": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "The runCommand id",
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"results": schema.MapNestedAttribute{
+				Computed:            true,
+				MarkdownDescription: "The results of the command, keyed by node name.",
+				NestedObject: schema.NestedAttributeObject{
+					Attributes: map[string]schema.Attribute{
+						"exit_code": schema.Int64Attribute{
+							Computed:            true,
+							MarkdownDescription: "The exit code of the command on the node",
+						},
+						"output": schema.StringAttribute{
+							Computed:            true,
+							MarkdownDescription: "The output of the command on the node",
+						},
+					},
+				},
+				PlanModifiers: []planmodifier.Map{
+					mapplanmodifier.UseStateForUnknown(),
+				},
+			},
+		},
+	}
+}
+
+func (r *NodeCommandResource) ValidateConfig(ctx context.Context, req resource.ValidateConfigRequest, resp *resource.ValidateConfigResponse) {
+	var data NodeCommandModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(validateNodeCommandConfig(data)...)
+}
+
+// ModifyPlan rejects commands, which are denied by the command policy of the provider, at plan time.
+func (r *NodeCommandResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	// Nothing is executed on destroy.
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+
+	var command types.String
+
+	resp.Diagnostics.Append(req.Plan.GetAttribute(ctx, path.Root("command"), &command)...)
+
+	// Unknown commands are checked by runNodeCommand before they are sent.
+	if resp.Diagnostics.HasError() || command.IsUnknown() || command.IsNull() {
+		return
+	}
+
+	addCommandPolicyError(&resp.Diagnostics, path.Root("command"), r.data, command.ValueString())
+}
+
+func (r *NodeCommandResource) Configure(_ context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(AzureAksCommandClient)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected AzureAksCommandClient, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	r.data = data
+}
+
+func (r *NodeCommandResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var data *NodeCommandModel
+
+	// Read Terraform plan data into the model
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &data)...)
+
+	// Prevent panic if the provider has not been configured.
+	if r.data.managedClustersClient == nil || r.data.tokenCredential == nil {
+		resp.Diagnostics.AddError(
+			"Unconfigured Client",
+			"Expected configured client. Please report this issue to the provider developers.",
+		)
+	}
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	resp.Diagnostics.Append(runNodeCommand(ctx, r.data, data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *NodeCommandResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data *NodeCommandModel
+
+	// Read Terraform prior state data into the model
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// Save updated data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *NodeCommandResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var data *NodeCommandModel
+
+	var plan *NodeCommandModel
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	// All other attributes force a new resource, only lock_group can be updated in-place.
+	data.LockGroup = plan.LockGroup
+
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+func (r *NodeCommandResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var data *NodeCommandModel
+
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+}
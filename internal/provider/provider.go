@@ -3,21 +3,26 @@ package provider
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/cloud"
+	azfake "github.com/Azure/azure-sdk-for-go/sdk/azcore/fake"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
-	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/containerservice/armcontainerservice/v9"
+	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/provider/schema"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
 	"github.com/jkroepke/terraform-provider-azureakscommand/internal/clients"
 	"github.com/jkroepke/terraform-provider-azureakscommand/internal/helpers"
 
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
 	"github.com/hashicorp/terraform-plugin-framework/provider"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/types"
@@ -25,6 +30,14 @@ import (
 
 // Ensure AzureAksCommandProvider satisfies various provider interfaces.
 var _ provider.Provider = &AzureAksCommandProvider{}
+var _ provider.ProviderWithValidateConfig = &AzureAksCommandProvider{}
+
+// cloudEnvironments maps the values of the environment attribute to the cloud configurations.
+var cloudEnvironments = map[string]cloud.Configuration{
+	"public":       cloud.AzurePublic,
+	"usgovernment": cloud.AzureGovernment,
+	"china":        cloud.AzureChina,
+}
 
 // AzureAksCommandProvider defines the provider implementation.
 type AzureAksCommandProvider struct {
@@ -32,33 +45,69 @@ type AzureAksCommandProvider struct {
 	// provider is built and ran locally, and "test" when running acceptance
 	// testing.
 	version string
+
+	// tokenCredential and managedClustersClient replace the Azure clients created in Configure, e.g. to run the
+	// provider offline against a fake backend in tests.
+	tokenCredential       azcore.TokenCredential
+	managedClustersClient clients.ManagedClustersAPI
 }
 
 // AzureAksCommandProviderModel describes the provider data model.
 type AzureAksCommandProviderModel struct {
-	SubscriptionId            types.String `tfsdk:"subscription_id"`
-	ClientId                  types.String `tfsdk:"client_id"`
-	TenantId                  types.String `tfsdk:"tenant_id"`
-	Environment               types.String `tfsdk:"environment"`
-	ClientCertificatePath     types.String `tfsdk:"client_certificate_path"`
-	ClientCertificatePassword types.String `tfsdk:"client_certificate_password"`
-	ClientSecret              types.String `tfsdk:"client_secret"`
-	OidcRequestToken          types.String `tfsdk:"oidc_request_token"`
-	OidcRequestUrl            types.String `tfsdk:"oidc_request_url"`
-	OidcToken                 types.String `tfsdk:"oidc_token"`
-	OidcTokenFilePath         types.String `tfsdk:"oidc_token_file_path"`
-	UseOidc                   types.Bool   `tfsdk:"use_oidc"`
-	UseMsi                    types.Bool   `tfsdk:"use_msi"`
-	MsiEndpoint               types.String `tfsdk:"msi_endpoint"`
-	PartnerId                 types.String `tfsdk:"partner_id"`
-	DisableTerraformPartnerId types.Bool   `tfsdk:"disable_terraform_partner_id"`
+	SubscriptionId                  types.String            `tfsdk:"subscription_id"`
+	ClientId                        types.String            `tfsdk:"client_id"`
+	TenantId                        types.String            `tfsdk:"tenant_id"`
+	AuxiliaryTenantIds              types.List              `tfsdk:"auxiliary_tenant_ids"`
+	Environment                     types.String            `tfsdk:"environment"`
+	MetadataHost                    types.String            `tfsdk:"metadata_host"`
+	ActiveDirectoryAuthorityHost    types.String            `tfsdk:"active_directory_authority_host"`
+	ResourceManagerEndpoint         types.String            `tfsdk:"resource_manager_endpoint"`
+	ClientCertificatePath           types.String            `tfsdk:"client_certificate_path"`
+	ClientCertificatePassword       types.String            `tfsdk:"client_certificate_password"`
+	ClientSecret                    types.String            `tfsdk:"client_secret"`
+	OidcRequestToken                types.String            `tfsdk:"oidc_request_token"`
+	OidcRequestUrl                  types.String            `tfsdk:"oidc_request_url"`
+	OidcToken                       types.String            `tfsdk:"oidc_token"`
+	OidcTokenFilePath               types.String            `tfsdk:"oidc_token_file_path"`
+	OidcAzureServiceConnectionId    types.String            `tfsdk:"oidc_azure_service_connection_id"`
+	UseOidc                         types.Bool              `tfsdk:"use_oidc"`
+	UseMsi                          types.Bool              `tfsdk:"use_msi"`
+	MsiEndpoint                     types.String            `tfsdk:"msi_endpoint"`
+	MsiObjectId                     types.String            `tfsdk:"msi_object_id"`
+	MsiResourceId                   types.String            `tfsdk:"msi_resource_id"`
+	UseCli                          types.Bool              `tfsdk:"use_cli"`
+	UseAzd                          types.Bool              `tfsdk:"use_azd"`
+	PartnerId                       types.String            `tfsdk:"partner_id"`
+	DisableTerraformPartnerId       types.Bool              `tfsdk:"disable_terraform_partner_id"`
+	ProxyUrl                        types.String            `tfsdk:"proxy_url"`
+	CACertificates                  types.String            `tfsdk:"ca_certificates"`
+	CACertificatesPath              types.String            `tfsdk:"ca_certificates_path"`
+	RequestTimeout                  types.String            `tfsdk:"request_timeout"`
+	ClusterTokenScope               types.String            `tfsdk:"cluster_token_scope"`
+	ClusterCredential               *ClusterCredentialModel `tfsdk:"cluster_credential"`
+	Retry                           *RetryModel             `tfsdk:"retry"`
+	MaxConcurrentCommands           types.Int64             `tfsdk:"max_concurrent_commands"`
+	MaxConcurrentCommandsPerCluster types.Int64             `tfsdk:"max_concurrent_commands_per_cluster"`
+	MockResponses                   types.String            `tfsdk:"mock_responses"`
+	AllowedCommandPatterns          types.List              `tfsdk:"allowed_command_patterns"`
+	DeniedCommandPatterns           types.List              `tfsdk:"denied_command_patterns"`
 }
 
 type AzureAksCommandClient struct {
-	tokenCredential       azcore.TokenCredential
-	managedClustersClient *armcontainerservice.ManagedClustersClient
+	tokenCredential        azcore.TokenCredential
+	clusterTokenCredential azcore.TokenCredential
+	clusterTokenScope      string
+	resourceManagerScope   string
+	auxiliaryTenantIds     []string
+	limiter                *commandLimiter
+	cache                  *clusterCache
+	commandPolicy          *commandPolicy
+	managedClustersClient  clients.ManagedClustersAPI
 }
 
+// defaultClusterTokenScope is the application ID of the AKS AAD server, which is the audience of the cluster token.
+const defaultClusterTokenScope = "6dae42f8-4368-4678-94ff-3960e28e3630"
+
 func New(version string) func() provider.Provider {
 	return func() provider.Provider {
 		return &AzureAksCommandProvider{
@@ -90,8 +139,25 @@ func (p *AzureAksCommandProvider) Schema(_ context.Context, _ provider.SchemaReq
 				MarkdownDescription: "The Tenant ID which should be used. This can also be sourced from the `ARM_TENANT_ID` or `AZURE_TENANT_ID` Environment Variables.",
 				Optional:            true,
 			},
+			"auxiliary_tenant_ids": schema.ListAttribute{
+				ElementType:         types.StringType,
+				MarkdownDescription: "The IDs of additional tenants, which contain clusters managed by a multi-tenant application. This can also be sourced from the `ARM_AUXILIARY_TENANT_IDS` Environment Variable as semicolon separated list.",
+				Optional:            true,
+			},
 			"environment": schema.StringAttribute{
-				MarkdownDescription: "The Cloud Environment which should be used. Possible values are `public`, `usgovernment`, and `china`. Defaults to `public`. This can also be sourced from the `ARM_ENVIRONMENT` or `AZURE_ENVIRONMENT` Environment Variables.",
+				MarkdownDescription: "The Cloud Environment which should be used. Possible values are `public`, `usgovernment`, and `china`. Defaults to `public`. If `metadata_host` is set, any environment name served by the metadata endpoint is allowed. This can also be sourced from the `ARM_ENVIRONMENT` or `AZURE_ENVIRONMENT` Environment Variables.",
+				Optional:            true,
+			},
+			"metadata_host": schema.StringAttribute{
+				MarkdownDescription: "The Hostname of the Azure Resource Manager, which serves the cloud configuration through the metadata endpoint, e.g. for Azure Stack. This can also be sourced from the `ARM_METADATA_HOSTNAME` Environment Variable.",
+				Optional:            true,
+			},
+			"active_directory_authority_host": schema.StringAttribute{
+				MarkdownDescription: "The Microsoft Entra authority host, e.g. `https://login.microsoftonline.com/`. Overrides the value of the cloud environment. This can also be sourced from the `ARM_ACTIVE_DIRECTORY_AUTHORITY_HOST` Environment Variable.",
+				Optional:            true,
+			},
+			"resource_manager_endpoint": schema.StringAttribute{
+				MarkdownDescription: "The Azure Resource Manager endpoint, e.g. `https://management.azure.com/`. Overrides the value of the cloud environment. This can also be sourced from the `ARM_RESOURCE_MANAGER_ENDPOINT` Environment Variable.",
 				Optional:            true,
 			},
 
@@ -116,11 +182,11 @@ func (p *AzureAksCommandProvider) Schema(_ context.Context, _ provider.SchemaReq
 
 			// OIDC specific fields
 			"oidc_request_token": schema.StringAttribute{
-				MarkdownDescription: "The bearer token for the request to the OIDC provider. This can also be sourced from the `ARM_OIDC_REQUEST_TOKEN` or `ACTIONS_ID_TOKEN_REQUEST_TOKEN` Environment Variables.",
+				MarkdownDescription: "The bearer token for the request to the OIDC provider. This can also be sourced from the `ARM_OIDC_REQUEST_TOKEN`, `ACTIONS_ID_TOKEN_REQUEST_TOKEN` or `SYSTEM_ACCESSTOKEN` Environment Variables.",
 				Optional:            true,
 			},
 			"oidc_request_url": schema.StringAttribute{
-				MarkdownDescription: "The URL for the OIDC provider from which to request an ID token. This can also be sourced from the `ARM_OIDC_REQUEST_URL` or `ACTIONS_ID_TOKEN_REQUEST_URL` Environment Variables.",
+				MarkdownDescription: "The URL for the OIDC provider from which to request an ID token. This can also be sourced from the `ARM_OIDC_REQUEST_URL`, `ACTIONS_ID_TOKEN_REQUEST_URL` or `SYSTEM_OIDCREQUESTURI` Environment Variables.",
 				Optional:            true,
 			},
 			"oidc_token": schema.StringAttribute{
@@ -132,20 +198,42 @@ func (p *AzureAksCommandProvider) Schema(_ context.Context, _ provider.SchemaReq
 				MarkdownDescription: "The path to a file containing an ID token when authenticating using OpenID Connect (OIDC). This can also be sourced from the `ARM_OIDC_TOKEN_FILE_PATH` or `AZURE_FEDERATED_TOKEN_FILE` environment Variable.",
 				Optional:            true,
 			},
+			"oidc_azure_service_connection_id": schema.StringAttribute{
+				MarkdownDescription: "The Azure DevOps service connection ID, which is used to request an ID token from Azure Pipelines. This can also be sourced from the `ARM_OIDC_AZURE_SERVICE_CONNECTION_ID` or `ARM_ADO_PIPELINE_SERVICE_CONNECTION_ID` Environment Variables.",
+				Optional:            true,
+			},
 			"use_oidc": schema.BoolAttribute{
-				MarkdownDescription: "Should OIDC be used for Authentication? This can also be sourced from the `ARM_USE_OIDC` Environment Variable. Defaults to `false`.",
+				MarkdownDescription: "Should OIDC be used for Authentication? The ID token is sourced from `oidc_token`, `oidc_token_file_path`, Azure DevOps (`oidc_azure_service_connection_id`), GitHub Actions or the `TFC_WORKLOAD_IDENTITY_TOKEN` Environment Variable of HCP Terraform. This can also be sourced from the `ARM_USE_OIDC` Environment Variable. Defaults to `false`.",
 				Optional:            true,
 			},
 
 			// Managed Service Identity specific fields
 			"use_msi": schema.BoolAttribute{
-				MarkdownDescription: "Allowed Managed Service Identity be used for Authentication.",
+				MarkdownDescription: "Allowed Managed Service Identity be used for Authentication. A user-assigned identity can be selected through `client_id`, `msi_object_id` or `msi_resource_id`. This can also be sourced from the `ARM_USE_MSI` Environment Variable. If not set, the managed identity is used when it's available and neither a client secret, a client certificate nor OIDC is configured, like in the discovery of `DefaultAzureCredential`. Set to `false` to disable the detection.",
 				Optional:            true,
 			},
 			"msi_endpoint": schema.StringAttribute{
 				MarkdownDescription: "The path to a custom endpoint for Managed Service Identity - in most circumstances, this should be detected automatically. This can also, be sourced from the `ARM_MSI_ENDPOINT` or `MSI_ENDPOINT` Environment Variable.",
 				Optional:            true,
 			},
+			"msi_object_id": schema.StringAttribute{
+				MarkdownDescription: "The Object ID of the user-assigned Managed Service Identity, which should be used. Conflicts with `client_id` and `msi_resource_id`. This can also be sourced from the `ARM_MSI_OBJECT_ID` Environment Variable.",
+				Optional:            true,
+			},
+			"msi_resource_id": schema.StringAttribute{
+				MarkdownDescription: "The Resource ID of the user-assigned Managed Service Identity, which should be used. Conflicts with `client_id` and `msi_object_id`. This can also be sourced from the `ARM_MSI_RESOURCE_ID` Environment Variable.",
+				Optional:            true,
+			},
+
+			// Azure CLI specific fields
+			"use_cli": schema.BoolAttribute{
+				MarkdownDescription: "Should the Azure CLI be used for Authentication? The tenant of `tenant_id` is passed to the Azure CLI. This can also be sourced from the `ARM_USE_CLI` Environment Variable. Defaults to `true`.",
+				Optional:            true,
+			},
+			"use_azd": schema.BoolAttribute{
+				MarkdownDescription: "Should the Azure Developer CLI be used for Authentication? The tenant of `tenant_id` is passed to the Azure Developer CLI. This can also be sourced from the `ARM_USE_AZD` Environment Variable. Defaults to `false`.",
+				Optional:            true,
+			},
 
 			// Managed Tracking GUID for User-agent
 			"partner_id": schema.StringAttribute{
@@ -156,6 +244,119 @@ func (p *AzureAksCommandProvider) Schema(_ context.Context, _ provider.SchemaReq
 				MarkdownDescription: "Disable sending the Terraform Partner ID if a custom partner_id isn't specified, which allows Microsoft to better understand the usage of Terraform. The Partner ID does not give the author any direct access to usage information. This can also be sourced from the `ARM_DISABLE_TERRAFORM_PARTNER_ID` environment variable. Defaults to `false`.",
 				Optional:            true,
 			},
+
+			// HTTP transport specific fields
+			"proxy_url": schema.StringAttribute{
+				MarkdownDescription: "The URL of the HTTP proxy, which should be used for all requests. This can also be sourced from the `ARM_PROXY_URL` Environment Variable. Defaults to the `HTTPS_PROXY`, `HTTP_PROXY` and `NO_PROXY` Environment Variables.",
+				Optional:            true,
+			},
+			"ca_certificates": schema.StringAttribute{
+				MarkdownDescription: "PEM encoded CA certificates, which are trusted in addition to the system certificates, e.g. of a TLS-inspecting proxy.",
+				Optional:            true,
+			},
+			"ca_certificates_path": schema.StringAttribute{
+				MarkdownDescription: "The path to a file containing PEM encoded CA certificates, which are trusted in addition to the system certificates. This can also be sourced from the `ARM_CA_CERTIFICATES_PATH` Environment Variable.",
+				Optional:            true,
+			},
+			"request_timeout": schema.StringAttribute{
+				MarkdownDescription: "The timeout of a single HTTP request, e.g. `60s`. This can also be sourced from the `ARM_REQUEST_TIMEOUT` Environment Variable. Defaults to no timeout.",
+				Optional:            true,
+			},
+
+			// Concurrency specific fields
+			"max_concurrent_commands": schema.Int64Attribute{
+				MarkdownDescription: "The maximum number of commands, which run concurrently across all clusters. Defaults to no limit.",
+				Optional:            true,
+			},
+			"max_concurrent_commands_per_cluster": schema.Int64Attribute{
+				MarkdownDescription: "The maximum number of commands, which run concurrently on a single cluster. Defaults to no limit.",
+				Optional:            true,
+			},
+			"mock_responses": schema.StringAttribute{
+				MarkdownDescription: "The path to a JSON file with canned responses, e.g. for `terraform test`. If set, commands aren't sent to Azure and no credentials are required. " +
+					"The file contains a list of objects with the regular expressions `cluster` (matched against `<resource_group_name>/<name>`) and `command`, and the response `exit_code`, `output`, `provisioning_state` and `reason`. " +
+					"The first matching entry is returned, commands without matching entry fail. Clusters without matching entry are reported as not found, and commands with `provisioning_state` `Failed` fail like in Azure. This can also be sourced from the `ARM_AKSCOMMAND_MOCK_FILE` Environment Variable.",
+				Optional: true,
+			},
+
+			// Command policy specific fields
+			"allowed_command_patterns": schema.ListAttribute{
+				MarkdownDescription: "A list of regular expressions. If set, only commands matching at least one of them are executed. " +
+					"The expressions may match anywhere in the command, anchor them with `^` and `$` where required. " +
+					"The patterns are checked against every command sent to runCommand, including the `kubectl wait` and `kubectl get` commands of `azureakscommand_wait`, the test command `true` of `azureakscommand_diagnostics` and the `run` subcommand of the provider binary. " +
+					"For `azureakscommand_node_command` the `command` is checked instead of the script running it on the nodes, for `azureakscommand_job` the `image` followed by the `command` and `args` of the container, separated by spaces, e.g. `busybox:1.36 sh -c date`, instead of the `kubectl` commands submitting and watching the Job. " +
+					"The files of `context` are not inspected, scripts shipped in it are only restricted through the command invoking them. " +
+					"This can also be sourced from the `ARM_AKSCOMMAND_ALLOWED_COMMAND_PATTERNS` Environment Variable as newline separated list.",
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+			"denied_command_patterns": schema.ListAttribute{
+				MarkdownDescription: "A list of regular expressions. Commands matching any of them are rejected, e.g. `kubectl\\s+delete\\s+(ns|namespace)` or `helm\\s+uninstall`. " +
+					"Takes precedence over `allowed_command_patterns` and is checked against the same commands. " +
+					"Rejected commands fail at plan time or, if the command is unknown until apply, before anything is sent to the cluster. " +
+					"This can also be sourced from the `ARM_AKSCOMMAND_DENIED_COMMAND_PATTERNS` Environment Variable as newline separated list.",
+				ElementType: types.StringType,
+				Optional:    true,
+			},
+
+			// AKS cluster token specific fields
+			"cluster_token_scope": schema.StringAttribute{
+				MarkdownDescription: "The scope of the token, which is passed to runCommand to authenticate against the Kubernetes API of AAD enabled clusters. Set this for clusters using a custom server application. This can also be sourced from the `ARM_CLUSTER_TOKEN_SCOPE` Environment Variable. Defaults to `" + defaultClusterTokenScope + "`.",
+				Optional:            true,
+			},
+		},
+		Blocks: map[string]schema.Block{
+			"retry": retryBlock(),
+			"cluster_credential": schema.SingleNestedBlock{
+				MarkdownDescription: "A separate identity, which acquires the token for the Kubernetes API of AAD enabled clusters. " +
+					"The identity of the provider is still used for the Azure Resource Manager API. If not set, the identity of the provider is used for both.",
+				Attributes: map[string]schema.Attribute{
+					"tenant_id": schema.StringAttribute{
+						MarkdownDescription: "The Tenant ID which should be used. Defaults to the `tenant_id` of the provider.",
+						Optional:            true,
+					},
+					"client_id": schema.StringAttribute{
+						MarkdownDescription: "The Client ID which should be used.",
+						Optional:            true,
+					},
+					"client_secret": schema.StringAttribute{
+						MarkdownDescription: "The Client Secret which should be used.",
+						Optional:            true,
+						Sensitive:           true,
+					},
+					"client_certificate_path": schema.StringAttribute{
+						MarkdownDescription: "The path to the Client Certificate associated with the Service Principal.",
+						Optional:            true,
+						Sensitive:           true,
+					},
+					"client_certificate_password": schema.StringAttribute{
+						MarkdownDescription: "The password associated with the Client Certificate.",
+						Optional:            true,
+						Sensitive:           true,
+					},
+					"oidc_token": schema.StringAttribute{
+						MarkdownDescription: "The ID token when authenticating using OpenID Connect (OIDC).",
+						Optional:            true,
+						Sensitive:           true,
+					},
+					"oidc_token_file_path": schema.StringAttribute{
+						MarkdownDescription: "The path to a file containing an ID token when authenticating using OpenID Connect (OIDC).",
+						Optional:            true,
+					},
+					"use_oidc": schema.BoolAttribute{
+						MarkdownDescription: "Should OIDC be used for Authentication? Defaults to `false`.",
+						Optional:            true,
+					},
+					"use_msi": schema.BoolAttribute{
+						MarkdownDescription: "Should Managed Service Identity be used for Authentication? The user-assigned identity is selected by `client_id`. Defaults to `false`.",
+						Optional:            true,
+					},
+					"use_cli": schema.BoolAttribute{
+						MarkdownDescription: "Should the Azure CLI be used for Authentication? Defaults to `false`.",
+						Optional:            true,
+					},
+				},
+			},
 		},
 	}
 }
@@ -169,129 +370,305 @@ func (p *AzureAksCommandProvider) Configure(ctx context.Context, req provider.Co
 		return
 	}
 
-	subscriptionId := getStringAttributeFromEnvironment(data.SubscriptionId, []string{"ARM_SUBSCRIPTION_ID", "AZURE_SUBSCRIPTION_ID"}, "")
+	aksCommandClient, diags := p.newAksCommandClient(ctx, data, req.TerraformVersion)
+	resp.Diagnostics.Append(diags...)
 
-	if subscriptionId == "" {
-		resp.Diagnostics.AddError("Missing subscription_id", "Could not detect subscription id through ARM_SUBSCRIPTION_ID, AZURE_SUBSCRIPTION_ID or provider attribute.")
+	if resp.Diagnostics.HasError() {
 		return
 	}
 
-	setAttributeFromTerraformOrEnvironment(data.TenantId, "AZURE_TENANT_ID", []string{"ARM_TENANT_ID", "AZURE_TENANT_ID"}, "")
-	setAttributeFromTerraformOrEnvironment(data.ClientId, "AZURE_CLIENT_ID", []string{"ARM_CLIENT_ID"}, "")
-	setAttributeFromTerraformOrEnvironment(data.ClientSecret, "AZURE_CLIENT_SECRET", []string{"ARM_CLIENT_SECRET"}, "")
-	setAttributeFromTerraformOrEnvironment(data.ClientCertificatePath, "AZURE_CERTIFICATE_PATH", []string{"ARM_CLIENT_CERTIFICATE_PATH"}, "")
-	setAttributeFromTerraformOrEnvironment(data.ClientCertificatePassword, "AZURE_CERTIFICATE_PASSWORD", []string{"ARM_CLIENT_CERTIFICATE_PASSWORD"}, "")
-	setAttributeFromTerraformOrEnvironment(data.Environment, "AZURE_ENVIRONMENT", []string{"ARM_ENVIRONMENT"}, "public")
-
-	if getBooleanAttributeFromEnvironment(data.UseMsi, []string{"ARM_USE_MSI"}, false) {
-		setAttributeFromTerraformOrEnvironment(data.MsiEndpoint, "MSI_ENDPOINT", []string{"ARM_MSI_ENDPOINT"}, "")
-	} else if getBooleanAttributeFromEnvironment(data.UseOidc, []string{"ARM_USE_OIDC"}, false) {
-		setAttributeFromTerraformOrEnvironment(data.OidcTokenFilePath, "AZURE_FEDERATED_TOKEN_FILE", []string{"ARM_OIDC_TOKEN_FILE_PATH"}, "")
+	resp.DataSourceData = aksCommandClient
+	resp.ResourceData = aksCommandClient
+}
 
-		token := getStringAttributeFromEnvironment(data.OidcToken, []string{"ARM_OIDC_TOKEN"}, "")
+// newAksCommandClient resolves the credentials and creates the Azure clients from the provider configuration and the
+// environment. It's shared by Configure and the run subcommand of the provider binary.
+func (p *AzureAksCommandProvider) newAksCommandClient(ctx context.Context, data AzureAksCommandProviderModel, terraformVersion string) (AzureAksCommandClient, diag.Diagnostics) {
+	var diags diag.Diagnostics
 
-		oidcRequestUrl := getStringAttributeFromEnvironment(data.OidcRequestUrl, []string{"ARM_OIDC_REQUEST_URL", "ACTIONS_ID_TOKEN_REQUEST_URL"}, "")
-		oidcRequestToken := getStringAttributeFromEnvironment(data.OidcRequestToken, []string{"ARM_OIDC_REQUEST_TOKEN", "ACTIONS_ID_TOKEN_REQUEST_TOKEN"}, "")
+	commandPolicy, err := newCommandPolicy(
+		getStringListAttributeFromEnvironment(data.AllowedCommandPatterns, []string{"ARM_AKSCOMMAND_ALLOWED_COMMAND_PATTERNS"}, "\n"),
+		getStringListAttributeFromEnvironment(data.DeniedCommandPatterns, []string{"ARM_AKSCOMMAND_DENIED_COMMAND_PATTERNS"}, "\n"),
+	)
+	if err != nil {
+		diags.AddError("Error while configuring the command policy", err.Error())
+		return AzureAksCommandClient{}, diags
+	}
 
-		if token != "" && oidcRequestUrl != "" && oidcRequestToken != "" {
-			var err error
+	if mockResponsesPath := getStringAttributeFromEnvironment(data.MockResponses, []string{"ARM_AKSCOMMAND_MOCK_FILE"}, ""); mockResponsesPath != "" {
+		client, diags := newMockAksCommandClient(ctx, data, mockResponsesPath)
+		client.commandPolicy = commandPolicy
 
-			token, err = helpers.GetOidcTokenFromGithubActions(data.OidcRequestUrl.ValueString(), data.OidcRequestToken.ValueString())
-			if err != nil {
-				resp.Diagnostics.AddError("Error while request token from GH API", err.Error())
-				return
-			}
-		}
+		return client, diags
+	}
 
-		if token != "" {
-			f, err := os.CreateTemp("", "token*")
-			if err != nil {
-				resp.Diagnostics.AddError("Error while setup OIDC token file", err.Error())
-				return
-			}
+	subscriptionId := getStringAttributeFromEnvironment(data.SubscriptionId, []string{"ARM_SUBSCRIPTION_ID", "AZURE_SUBSCRIPTION_ID"}, "")
 
-			_, err = f.WriteString(token)
-			if err != nil {
-				resp.Diagnostics.AddError("Error while setup OIDC token file", err.Error())
-				return
-			}
+	if subscriptionId == "" {
+		diags.AddError("Missing subscription_id", "Could not detect subscription id through ARM_SUBSCRIPTION_ID, AZURE_SUBSCRIPTION_ID or provider attribute.")
+		return AzureAksCommandClient{}, diags
+	}
 
-			_ = os.Setenv("AZURE_FEDERATED_TOKEN_FILE", f.Name())
+	httpClient, err := newHttpClient(data)
+	if err != nil {
+		diags.AddError("Error while configuring the HTTP client", err.Error())
+		return AzureAksCommandClient{}, diags
+	}
 
-			defer func(name string) {
-				_ = os.Remove(name)
-			}(f.Name())
-		}
+	cloudConfig, err := p.getCloudConfig(ctx, data, httpClient)
+	if err != nil {
+		diags.AddError("Error while configuring the cloud environment", err.Error())
+		return AzureAksCommandClient{}, diags
 	}
 
 	partnerId := getStringAttributeFromEnvironment(data.PartnerId, []string{"ARM_PARTNER_ID"}, "")
 	disableTerraformPartnerId := getBooleanAttributeFromEnvironment(data.UseMsi, []string{"ARM_DISABLE_TERRAFORM_PARTNER_ID"}, false)
 
-	userAgent := buildUserAgent(req.TerraformVersion, p.version, disableTerraformPartnerId, partnerId)
+	userAgent := buildUserAgent(terraformVersion, p.version, disableTerraformPartnerId, partnerId)
 
-	cred, err := azidentity.NewDefaultAzureCredential(
-		&azidentity.DefaultAzureCredentialOptions{
-			ClientOptions: azcore.ClientOptions{
-				Cloud: p.getCloudConfig(data),
-				PerCallPolicies: []policy.Policy{
-					clients.WithUserAgent(userAgent),
-				},
-			},
+	clientOptions := azcore.ClientOptions{
+		Cloud:     cloudConfig,
+		Transport: httpClient,
+		PerCallPolicies: []policy.Policy{
+			clients.WithUserAgent(userAgent),
 		},
-	)
+	}
 
-	if err != nil {
-		resp.Diagnostics.AddError("Error while request token for AKS", err.Error())
-		return
+	credentialConfig := newCredentialConfig(data)
+	credentialConfig.HttpClient = httpClient
+
+	cred, credentialNames := p.tokenCredential, []string{"injected"}
+
+	if cred == nil {
+		cred, credentialNames, err = buildTokenCredential(credentialConfig, clientOptions)
+		if err != nil {
+			diags.AddError("Error while configuring Azure credentials", err.Error())
+			return AzureAksCommandClient{}, diags
+		}
 	}
 
-	client, err := armcontainerservice.NewManagedClustersClient(subscriptionId, cred, &arm.ClientOptions{
-		ClientOptions: azcore.ClientOptions{
-			Cloud: p.getCloudConfig(data),
-			PerCallPolicies: []policy.Policy{
-				clients.WithUserAgent(userAgent),
-			},
-		},
+	tflog.Info(ctx, "Configured Azure credential chain", map[string]interface{}{
+		"credentials": strings.Join(credentialNames, ", "),
 	})
 
-	if err != nil {
-		resp.Diagnostics.AddError("Error while request token for AKS", err.Error())
-		return
+	clusterTokenCredential := cred
+
+	if data.ClusterCredential != nil {
+		clusterTokenCredential, credentialNames, err = buildTokenCredential(newClusterCredentialConfig(*data.ClusterCredential, credentialConfig), clientOptions)
+		if err != nil {
+			diags.AddAttributeError(path.Root("cluster_credential"), "Error while configuring cluster credentials", err.Error())
+			return AzureAksCommandClient{}, diags
+		}
+
+		tflog.Info(ctx, "Configured Azure credential chain for the cluster token", map[string]interface{}{
+			"credentials": strings.Join(credentialNames, ", "),
+		})
+	}
+
+	armClientOptions := clientOptions
+
+	if len(credentialConfig.AuxiliaryTenantIds) > 0 {
+		armClientOptions.PerCallPolicies = append(armClientOptions.PerCallPolicies,
+			clients.WithAuxiliaryTenants(cred, cloudConfig.Services[cloud.ResourceManager].Audience, credentialConfig.AuxiliaryTenantIds),
+		)
 	}
 
-	resp.DataSourceData = AzureAksCommandClient{
-		tokenCredential:       cred,
-		managedClustersClient: client,
+	armClientOptions.PerRetryPolicies = []policy.Policy{
+		clients.WithThrottlingLogs(),
+		clients.WithLogging(),
 	}
-	resp.ResourceData = AzureAksCommandClient{
-		tokenCredential:       cred,
-		managedClustersClient: client,
+
+	if data.Retry != nil {
+		retryOptions, retryDiags := data.Retry.retryOptions()
+		diags.Append(retryDiags...)
+
+		if diags.HasError() {
+			return AzureAksCommandClient{}, diags
+		}
+
+		armClientOptions.Retry = retryOptions
+	}
+
+	var client clients.ManagedClustersAPI = p.managedClustersClient
+
+	if client == nil {
+		client, err = armcontainerservice.NewManagedClustersClient(subscriptionId, cred, &arm.ClientOptions{
+			ClientOptions: armClientOptions,
+		})
+
+		if err != nil {
+			diags.AddError("Error while request token for AKS", err.Error())
+			return AzureAksCommandClient{}, diags
+		}
+	}
+
+	aksCommandClient := AzureAksCommandClient{
+		tokenCredential:        cred,
+		clusterTokenCredential: clusterTokenCredential,
+		clusterTokenScope:      getStringAttributeFromEnvironment(data.ClusterTokenScope, []string{"ARM_CLUSTER_TOKEN_SCOPE"}, defaultClusterTokenScope),
+		resourceManagerScope:   cloudConfig.Services[cloud.ResourceManager].Audience + "/.default",
+		auxiliaryTenantIds:     credentialConfig.AuxiliaryTenantIds,
+		limiter:                newCommandLimiter(int(data.MaxConcurrentCommands.ValueInt64()), int(data.MaxConcurrentCommandsPerCluster.ValueInt64())),
+		cache:                  newClusterCache(),
+		commandPolicy:          commandPolicy,
+		managedClustersClient:  client,
 	}
+
+	return aksCommandClient, diags
+}
+
+// newMockAksCommandClient returns a client, which answers all commands from the mock_responses file instead of Azure.
+func newMockAksCommandClient(ctx context.Context, data AzureAksCommandProviderModel, mockResponsesPath string) (AzureAksCommandClient, diag.Diagnostics) {
+	var diags diag.Diagnostics
+
+	responses, err := loadMockResponses(mockResponsesPath)
+	if err != nil {
+		diags.AddError("Error while loading mock responses", err.Error())
+		return AzureAksCommandClient{}, diags
+	}
+
+	tflog.Warn(ctx, "Mock mode is enabled, commands are not sent to Azure", map[string]interface{}{
+		"mock_responses": mockResponsesPath,
+	})
+
+	aksCommandClient := AzureAksCommandClient{
+		tokenCredential:       &azfake.TokenCredential{},
+		limiter:               newCommandLimiter(int(data.MaxConcurrentCommands.ValueInt64()), int(data.MaxConcurrentCommandsPerCluster.ValueInt64())),
+		cache:                 newClusterCache(),
+		managedClustersClient: newMockManagedClustersClient(responses),
+	}
+
+	return aksCommandClient, diags
 }
 
 func (p *AzureAksCommandProvider) Resources(_ context.Context) []func() resource.Resource {
 	return []func() resource.Resource{
 		NewInvokeResource,
+		NewJobResource,
+		NewNodeCommandResource,
 	}
 }
 
 func (p *AzureAksCommandProvider) DataSources(_ context.Context) []func() datasource.DataSource {
 	return []func() datasource.DataSource{
+		NewClusterDataSource,
+		NewCommandResultDataSource,
+		NewDiagnosticsDataSource,
 		NewInvokeDataSource,
+		NewNodeCommandDataSource,
+		NewWaitDataSource,
+	}
+}
+
+func (p *AzureAksCommandProvider) ValidateConfig(ctx context.Context, req provider.ValidateConfigRequest, resp *provider.ValidateConfigResponse) {
+	var data AzureAksCommandProviderModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	if data.Retry != nil {
+		_, diags := data.Retry.retryOptions()
+		resp.Diagnostics.Append(diags...)
+	}
+
+	if _, err := compileCommandPatterns(stringListValues(data.AllowedCommandPatterns)); err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("allowed_command_patterns"), "Invalid command pattern", err.Error())
+	}
+
+	if _, err := compileCommandPatterns(stringListValues(data.DeniedCommandPatterns)); err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("denied_command_patterns"), "Invalid command pattern", err.Error())
+	}
+
+	// Custom environment names are resolved through the metadata endpoint.
+	if !data.MetadataHost.IsNull() || data.Environment.IsNull() || data.Environment.IsUnknown() {
+		return
+	}
+
+	if _, ok := cloudEnvironments[data.Environment.ValueString()]; !ok {
+		resp.Diagnostics.AddAttributeError(path.Root("environment"), "Unknown environment", fmt.Sprintf("Unknown environment %q. Possible values are public, usgovernment and china.", data.Environment.ValueString()))
+	}
+}
+
+// getCloudConfig returns the cloud configuration of the environment or of the metadata endpoint. The authority host
+// and the resource manager endpoint can be overridden individually.
+func (p *AzureAksCommandProvider) getCloudConfig(ctx context.Context, data AzureAksCommandProviderModel, httpClient *http.Client) (cloud.Configuration, error) {
+	environment := getStringAttributeFromEnvironment(data.Environment, []string{"ARM_ENVIRONMENT", "AZURE_ENVIRONMENT"}, "public")
+	metadataHost := getStringAttributeFromEnvironment(data.MetadataHost, []string{"ARM_METADATA_HOSTNAME"}, "")
+
+	var cloudConfig cloud.Configuration
+
+	if metadataHost != "" {
+		var err error
+
+		cloudConfig, err = helpers.GetCloudConfigurationFromMetadataHost(ctx, httpClient, metadataHost, environment)
+		if err != nil {
+			return cloud.Configuration{}, err
+		}
+	} else {
+		var ok bool
+
+		cloudConfig, ok = cloudEnvironments[environment]
+		if !ok {
+			return cloud.Configuration{}, fmt.Errorf("unknown environment %q, possible values are public, usgovernment and china", environment)
+		}
+	}
+
+	// The predefined configurations are shared, so the services are copied before modifying them.
+	services := make(map[cloud.ServiceName]cloud.ServiceConfiguration, len(cloudConfig.Services))
+	for name, service := range cloudConfig.Services {
+		services[name] = service
+	}
+
+	cloudConfig.Services = services
+
+	if authorityHost := getStringAttributeFromEnvironment(data.ActiveDirectoryAuthorityHost, []string{"ARM_ACTIVE_DIRECTORY_AUTHORITY_HOST"}, ""); authorityHost != "" {
+		cloudConfig.ActiveDirectoryAuthorityHost = authorityHost
+	}
+
+	if resourceManagerEndpoint := getStringAttributeFromEnvironment(data.ResourceManagerEndpoint, []string{"ARM_RESOURCE_MANAGER_ENDPOINT"}, ""); resourceManagerEndpoint != "" {
+		resourceManager := cloudConfig.Services[cloud.ResourceManager]
+		resourceManager.Endpoint = resourceManagerEndpoint
+
+		if resourceManager.Audience == "" {
+			resourceManager.Audience = resourceManagerEndpoint
+		}
+
+		cloudConfig.Services[cloud.ResourceManager] = resourceManager
 	}
+
+	return cloudConfig, nil
 }
 
-func (p *AzureAksCommandProvider) getCloudConfig(data AzureAksCommandProviderModel) cloud.Configuration {
-	switch data.Environment.ValueString() {
-	case "public":
-		return cloud.AzurePublic
-	case "usgovernment":
-		return cloud.AzureGovernment
-	case "china":
-		return cloud.AzureChina
-	default:
-		return cloud.AzurePublic
+// newHttpClient returns the HTTP client shared by the credentials, the ARM client and the OIDC token sources.
+func newHttpClient(data AzureAksCommandProviderModel) (*http.Client, error) {
+	options := clients.HttpClientOptions{
+		ProxyUrl:       getStringAttributeFromEnvironment(data.ProxyUrl, []string{"ARM_PROXY_URL"}, ""),
+		CACertificates: []byte(data.CACertificates.ValueString()),
+	}
+
+	if caCertificatesPath := getStringAttributeFromEnvironment(data.CACertificatesPath, []string{"ARM_CA_CERTIFICATES_PATH"}, ""); caCertificatesPath != "" {
+		caCertificates, err := os.ReadFile(caCertificatesPath)
+		if err != nil {
+			return nil, fmt.Errorf("reading CA certificates %q: %w", caCertificatesPath, err)
+		}
+
+		options.CACertificates = append(options.CACertificates, '\n')
+		options.CACertificates = append(options.CACertificates, caCertificates...)
 	}
+
+	if requestTimeout := getStringAttributeFromEnvironment(data.RequestTimeout, []string{"ARM_REQUEST_TIMEOUT"}, ""); requestTimeout != "" {
+		timeout, err := time.ParseDuration(requestTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("parsing request_timeout: %w", err)
+		}
+
+		options.Timeout = timeout
+	}
+
+	return clients.NewHttpClient(options)
 }
 
 func buildUserAgent(terraformVersion string, providerVersion string, disableTerraformPartnerId bool, partnerID string) string {
@@ -316,14 +693,6 @@ func buildUserAgent(terraformVersion string, providerVersion string, disableTerr
 	return userAgent
 }
 
-func setAttributeFromTerraformOrEnvironment(value types.String, envVar string, envVarNames []string, defaultValue string) {
-	configValue := getStringAttributeFromEnvironment(value, envVarNames, defaultValue)
-
-	if configValue != "" {
-		_ = os.Setenv(envVar, configValue)
-	}
-}
-
 func getStringAttributeFromEnvironment(value types.String, envVarNames []string, defaultValue string) string {
 	if !value.IsNull() {
 		return value.ValueString()
@@ -337,6 +706,31 @@ func getStringAttributeFromEnvironment(value types.String, envVarNames []string,
 
 	return defaultValue
 }
+
+// getStringListAttributeFromEnvironment returns the elements of the list or the separated values of the first
+// non-empty environment variable.
+func getStringListAttributeFromEnvironment(value types.List, envVarNames []string, separator string) []string {
+	if !value.IsNull() && !value.IsUnknown() {
+		return stringListValues(value)
+	}
+
+	for _, k := range envVarNames {
+		if v := os.Getenv(k); v != "" {
+			var values []string
+
+			for _, item := range strings.Split(v, separator) {
+				if item = strings.TrimSpace(item); item != "" {
+					values = append(values, item)
+				}
+			}
+
+			return values
+		}
+	}
+
+	return nil
+}
+
 func getBooleanAttributeFromEnvironment(value types.Bool, envVarNames []string, defaultValue bool) bool {
 	if !value.IsNull() {
 		return value.ValueBool()
@@ -3,8 +3,11 @@ package provider
 import (
 	"testing"
 
+	azfake "github.com/Azure/azure-sdk-for-go/sdk/azcore/fake"
 	"github.com/hashicorp/terraform-plugin-framework/providerserver"
 	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+
+	"github.com/jkroepke/terraform-provider-azureakscommand/internal/clients"
 )
 
 // testAccProtoV6ProviderFactories are used to instantiate a provider during
@@ -17,6 +20,27 @@ var testAccProtoV6ProviderFactories = map[string]func() (tfprotov6.ProviderServe
 	"azureakscommand": providerserver.NewProtocol6WithError(New("test")()),
 }
 
+// testAccProtoV6ProviderFactoriesWithFake returns provider factories, which run
+// the provider offline against the fake ARM backend.
+func testAccProtoV6ProviderFactoriesWithFake(t *testing.T, backend *FakeManagedClusters) map[string]func() (tfprotov6.ProviderServer, error) {
+	t.Helper()
+
+	managedClustersClient, err := backend.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("ARM_SUBSCRIPTION_ID", clients.FakeSubscriptionId)
+
+	return map[string]func() (tfprotov6.ProviderServer, error){
+		"azureakscommand": providerserver.NewProtocol6WithError(&AzureAksCommandProvider{
+			version:               "test",
+			tokenCredential:       &azfake.TokenCredential{},
+			managedClustersClient: managedClustersClient,
+		}),
+	}
+}
+
 //nolint:unused
 func testAccPreCheck(t *testing.T) {
 	// You can add code here to run prior to any test case execution, for example assertions
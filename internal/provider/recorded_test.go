@@ -0,0 +1,184 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	azfake "github.com/Azure/azure-sdk-for-go/sdk/azcore/fake"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/containerservice/armcontainerservice/v9"
+	"github.com/hashicorp/terraform-plugin-framework/providerserver"
+	"github.com/hashicorp/terraform-plugin-go/tfprotov6"
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+
+	"github.com/jkroepke/terraform-provider-azureakscommand/internal/clients"
+)
+
+const (
+	recordedResourceGroup = "rg-test"
+	recordedClusterName   = "aks-test"
+)
+
+// newRecordedClient returns a client, which replays the cassette testdata/cassettes/<name>.json, see
+// newRecordedAzureClients.
+func newRecordedClient(t *testing.T, name string) (AzureAksCommandClient, string, string) {
+	t.Helper()
+
+	credential, managedClustersClient, resourceGroup, clusterName := newRecordedAzureClients(t, name)
+
+	return AzureAksCommandClient{
+		tokenCredential:       credential,
+		limiter:               newCommandLimiter(0, 0),
+		cache:                 newClusterCache(),
+		managedClustersClient: managedClustersClient,
+	}, resourceGroup, clusterName
+}
+
+// testAccProtoV6ProviderFactoriesWithRecorder returns provider factories, which replay the cassette
+// testdata/cassettes/<name>.json, see newRecordedAzureClients.
+func testAccProtoV6ProviderFactoriesWithRecorder(t *testing.T, name string) (map[string]func() (tfprotov6.ProviderServer, error), string, string) {
+	t.Helper()
+
+	credential, managedClustersClient, resourceGroup, clusterName := newRecordedAzureClients(t, name)
+
+	if os.Getenv("ARM_AKSCOMMAND_RECORD") == "" {
+		t.Setenv("ARM_SUBSCRIPTION_ID", clients.FakeSubscriptionId)
+	}
+
+	return map[string]func() (tfprotov6.ProviderServer, error){
+		"azureakscommand": providerserver.NewProtocol6WithError(&AzureAksCommandProvider{
+			version:               "test",
+			tokenCredential:       credential,
+			managedClustersClient: managedClustersClient,
+		}),
+	}, resourceGroup, clusterName
+}
+
+// newRecordedAzureClients returns the credential and Managed Clusters client, which replay the cassette
+// testdata/cassettes/<name>.json. If ARM_AKSCOMMAND_RECORD is set, the cassette is recorded against the cluster
+// ARM_TEST_RESOURCE_GROUP and ARM_TEST_CLUSTER_NAME in ARM_SUBSCRIPTION_ID instead, using the credentials of
+// DefaultAzureCredential. The names of the cluster are replaced by recordedResourceGroup and recordedClusterName in
+// the cassette. Tests sending the same requests share a cassette, e.g. the invoke resource and data source.
+func newRecordedAzureClients(t *testing.T, name string) (azcore.TokenCredential, *armcontainerservice.ManagedClustersClient, string, string) {
+	t.Helper()
+
+	path := filepath.Join("testdata", "cassettes", name+".json")
+
+	if os.Getenv("ARM_AKSCOMMAND_RECORD") == "" {
+		recorder, err := clients.NewRecorder(path, clients.RecorderModeReplay, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		credential := &azfake.TokenCredential{}
+
+		return credential, newRecordedManagedClustersClient(t, clients.FakeSubscriptionId, credential, recorder), recordedResourceGroup, recordedClusterName
+	}
+
+	resourceGroup, clusterName := os.Getenv("ARM_TEST_RESOURCE_GROUP"), os.Getenv("ARM_TEST_CLUSTER_NAME")
+
+	recorder, err := clients.NewRecorder(path, clients.RecorderModeRecord, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recorder.Replacements = map[string]string{
+		resourceGroup: recordedResourceGroup,
+		clusterName:   recordedClusterName,
+	}
+
+	t.Cleanup(func() {
+		if err := recorder.Save(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	credential, err := azidentity.NewDefaultAzureCredential(&azidentity.DefaultAzureCredentialOptions{
+		ClientOptions: policy.ClientOptions{Transport: recorder},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return credential, newRecordedManagedClustersClient(t, os.Getenv("ARM_SUBSCRIPTION_ID"), credential, recorder), resourceGroup, clusterName
+}
+
+func newRecordedManagedClustersClient(t *testing.T, subscriptionId string, credential azcore.TokenCredential, transport policy.Transporter) *armcontainerservice.ManagedClustersClient {
+	t.Helper()
+
+	managedClustersClient, err := armcontainerservice.NewManagedClustersClient(subscriptionId, credential, &arm.ClientOptions{
+		ClientOptions: policy.ClientOptions{Transport: transport},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return managedClustersClient
+}
+
+func TestInvokeCommandRecorded(t *testing.T) {
+	client, resourceGroup, clusterName := newRecordedClient(t, "invoke")
+
+	data := newFakeInvokeModel(resourceGroup, clusterName, "kubectl get namespace default -o name", true)
+	if err := invokeCommand(context.Background(), client, data); err != nil {
+		t.Fatal(err)
+	}
+
+	if data.ExitCode.ValueInt64() != 0 || data.Output.ValueString() != "namespace/default\n" {
+		t.Errorf("unexpected result: output %q, exit_code %d", data.Output.ValueString(), data.ExitCode.ValueInt64())
+	}
+}
+
+func TestAccInvokeResourceRecorded(t *testing.T) {
+	factories, resourceGroup, clusterName := testAccProtoV6ProviderFactoriesWithRecorder(t, "invoke")
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: factories,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+resource "azureakscommand_invoke" "test" {
+  resource_group_name = %q
+  name                = %q
+  command             = "kubectl get namespace default -o name"
+}
+`, resourceGroup, clusterName),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("azureakscommand_invoke.test", "output", "namespace/default\n"),
+					resource.TestCheckResourceAttr("azureakscommand_invoke.test", "exit_code", "0"),
+					resource.TestCheckResourceAttr("azureakscommand_invoke.test", "provisioning_state", "Succeeded"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccInvokeDataSourceRecorded(t *testing.T) {
+	factories, resourceGroup, clusterName := testAccProtoV6ProviderFactoriesWithRecorder(t, "invoke")
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: factories,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(`
+data "azureakscommand_invoke" "test" {
+  resource_group_name = %q
+  name                = %q
+  command             = "kubectl get namespace default -o name"
+}
+`, resourceGroup, clusterName),
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.azureakscommand_invoke.test", "output", "namespace/default\n"),
+					resource.TestCheckResourceAttr("data.azureakscommand_invoke.test", "exit_code", "0"),
+					resource.TestCheckResourceAttr("data.azureakscommand_invoke.test", "provisioning_state", "Succeeded"),
+				),
+			},
+		},
+	})
+}
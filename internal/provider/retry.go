@@ -0,0 +1,110 @@
+package provider
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/provider/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// RetryModel describes the retry block of the provider.
+type RetryModel struct {
+	MaxRetries    types.Int64  `tfsdk:"max_retries"`
+	RetryDelay    types.String `tfsdk:"retry_delay"`
+	MaxRetryDelay types.String `tfsdk:"max_retry_delay"`
+	TryTimeout    types.String `tfsdk:"try_timeout"`
+	StatusCodes   types.List   `tfsdk:"status_codes"`
+}
+
+func retryBlock() schema.Block {
+	return schema.SingleNestedBlock{
+		MarkdownDescription: "Configures how requests against the Azure Resource Manager API are retried, e.g. if they are throttled. " +
+			"A `Retry-After` header returned by Azure takes precedence over the configured delays, this applies to runCommand and to polling its result.",
+		Attributes: map[string]schema.Attribute{
+			"max_retries": schema.Int64Attribute{
+				MarkdownDescription: "The maximum number of retries of a failed request. `0` or a value less than zero disables retries. Defaults to `3`.",
+				Optional:            true,
+			},
+			"retry_delay": schema.StringAttribute{
+				MarkdownDescription: "The initial delay between retries, which increases exponentially with each retry, e.g. `1s`. Defaults to `800ms`.",
+				Optional:            true,
+			},
+			"max_retry_delay": schema.StringAttribute{
+				MarkdownDescription: "The maximum delay between retries, e.g. `2m`. Defaults to `60s`.",
+				Optional:            true,
+			},
+			"try_timeout": schema.StringAttribute{
+				MarkdownDescription: "The maximum time of a single try of a request, e.g. `1m`. Defaults to no timeout.",
+				Optional:            true,
+			},
+			"status_codes": schema.ListAttribute{
+				ElementType:         types.Int64Type,
+				MarkdownDescription: "The HTTP status codes, which are retried. Defaults to `408`, `429`, `500`, `502`, `503` and `504`.",
+				Optional:            true,
+			},
+		},
+	}
+}
+
+// retryOptions maps the retry block to the retry options of the Azure SDK. Unset attributes keep the SDK defaults.
+func (m RetryModel) retryOptions() (policy.RetryOptions, diag.Diagnostics) {
+	var options policy.RetryOptions
+	var diags diag.Diagnostics
+
+	if !m.MaxRetries.IsNull() {
+		options.MaxRetries = int32(m.MaxRetries.ValueInt64())
+
+		// The Azure SDK replaces 0 by its default of 3 retries, retries are only disabled by a negative value.
+		if options.MaxRetries <= 0 {
+			options.MaxRetries = -1
+		}
+	}
+
+	durations := []struct {
+		name  string
+		value types.String
+		dest  *time.Duration
+	}{
+		{"retry_delay", m.RetryDelay, &options.RetryDelay},
+		{"max_retry_delay", m.MaxRetryDelay, &options.MaxRetryDelay},
+		{"try_timeout", m.TryTimeout, &options.TryTimeout},
+	}
+
+	for _, duration := range durations {
+		if duration.value.IsNull() || duration.value.IsUnknown() {
+			continue
+		}
+
+		d, err := time.ParseDuration(duration.value.ValueString())
+		if err != nil {
+			diags.AddAttributeError(path.Root("retry").AtName(duration.name), "Invalid duration", err.Error())
+			continue
+		}
+
+		*duration.dest = d
+	}
+
+	if !m.StatusCodes.IsNull() && !m.StatusCodes.IsUnknown() {
+		options.StatusCodes = []int{}
+
+		for _, element := range m.StatusCodes.Elements() {
+			statusCode, ok := element.(types.Int64)
+			if !ok || statusCode.IsNull() || statusCode.IsUnknown() {
+				continue
+			}
+
+			if statusCode.ValueInt64() < 100 || statusCode.ValueInt64() > 599 {
+				diags.AddAttributeError(path.Root("retry").AtName("status_codes"), "Invalid status code", fmt.Sprintf("%d is not a valid HTTP status code.", statusCode.ValueInt64()))
+				continue
+			}
+
+			options.StatusCodes = append(options.StatusCodes, int(statusCode.ValueInt64()))
+		}
+	}
+
+	return options, diags
+}
@@ -0,0 +1,57 @@
+package provider
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func TestRetryOptions(t *testing.T) {
+	tests := []struct {
+		name           string
+		model          RetryModel
+		wantMaxRetries int32
+		wantErr        bool
+	}{
+		{name: "defaults", model: RetryModel{}, wantMaxRetries: 0},
+		{name: "retries", model: RetryModel{MaxRetries: types.Int64Value(5)}, wantMaxRetries: 5},
+		{name: "zero disables retries", model: RetryModel{MaxRetries: types.Int64Value(0)}, wantMaxRetries: -1},
+		{name: "negative disables retries", model: RetryModel{MaxRetries: types.Int64Value(-3)}, wantMaxRetries: -1},
+		{name: "invalid duration", model: RetryModel{RetryDelay: types.StringValue("soon")}, wantErr: true},
+		{
+			name:    "invalid status code",
+			model:   RetryModel{StatusCodes: types.ListValueMust(types.Int64Type, []attr.Value{types.Int64Value(42)})},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options, diags := tt.model.retryOptions()
+
+			if diags.HasError() != tt.wantErr {
+				t.Fatalf("unexpected diagnostics: %v", diags)
+			}
+
+			if !tt.wantErr && options.MaxRetries != tt.wantMaxRetries {
+				t.Errorf("MaxRetries = %d, want %d", options.MaxRetries, tt.wantMaxRetries)
+			}
+		})
+	}
+
+	options, diags := RetryModel{
+		MaxRetries:  types.Int64Null(),
+		RetryDelay:  types.StringValue("2s"),
+		TryTimeout:  types.StringValue("1m"),
+		StatusCodes: types.ListValueMust(types.Int64Type, []attr.Value{types.Int64Value(429)}),
+	}.retryOptions()
+	if diags.HasError() {
+		t.Fatal(diags)
+	}
+
+	if options.RetryDelay != 2*time.Second || options.TryTimeout != time.Minute || len(options.StatusCodes) != 1 || options.StatusCodes[0] != 429 {
+		t.Errorf("unexpected options: %+v", options)
+	}
+}
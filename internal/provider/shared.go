@@ -1,23 +1,41 @@
 package provider
 
 import (
+	"archive/zip"
+	"bytes"
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
 	"strings"
+	"time"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/containerservice/armcontainerservice/v9"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 )
 
 // InvokeModel describes the resource data model.
 type InvokeModel struct {
+	Name              types.String `tfsdk:"name"`
+	ResourceGroupName types.String `tfsdk:"resource_group_name"`
+	Command           types.String `tfsdk:"command"`
+	Context           types.String `tfsdk:"context"`
+	Triggers          types.Map    `tfsdk:"triggers"`
+	Wait              types.Bool   `tfsdk:"wait"`
+	LockGroup         types.String `tfsdk:"lock_group"`
+	CommandResultModel
+}
+
+// CommandResultModel describes the result of a runCommand execution.
+type CommandResultModel struct {
 	Id                 types.String `tfsdk:"id"`
-	Name               types.String `tfsdk:"name"`
-	ResourceGroupName  types.String `tfsdk:"resource_group_name"`
-	Command            types.String `tfsdk:"command"`
-	Context            types.String `tfsdk:"context"`
-	Triggers           types.Map    `tfsdk:"triggers"`
 	ExitCode           types.Int64  `tfsdk:"exit_code"`
 	Output             types.String `tfsdk:"output"`
 	ProvisioningState  types.String `tfsdk:"provisioning_state"`
@@ -27,87 +45,357 @@ type InvokeModel struct {
 }
 
 func runCommand(ctx context.Context, client AzureAksCommandClient, resourceGroup string, resourceName string, command string, commandContext string) (*armcontainerservice.ManagedClustersClientRunCommandResponse, error) {
+	release, err := acquireCommand(ctx, client, resourceGroup, resourceName)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	poller, _, err := beginRunCommand(ctx, client, resourceGroup, resourceName, command, commandContext)
+	if err != nil {
+		return nil, err
+	}
+
+	runCommandPoller, err := poller.PollUntilDone(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &runCommandPoller, nil
+}
+
+// failedRunCommandResult returns the result of a runCommand operation, which failed because its command reached the
+// provisioning state Failed, e.g. if the pod of the command couldn't be started. ok is false for other errors, like
+// rejected requests.
+func failedRunCommandResult(err error) (result armcontainerservice.RunCommandResult, ok bool) {
+	var responseErr *azcore.ResponseError
+	if !errors.As(err, &responseErr) || responseErr.RawResponse == nil {
+		return result, false
+	}
+
+	body, err := runtime.Payload(responseErr.RawResponse)
+	if err != nil || json.Unmarshal(body, &result) != nil {
+		return result, false
+	}
+
+	ok = result.Properties != nil && result.Properties.ProvisioningState != nil && strings.EqualFold(*result.Properties.ProvisioningState, "Failed")
+
+	return result, ok
+}
+
+// invokeCommand executes the command of the model and stores its result. Commands rejected by the command policy of
+// the provider are never sent.
+func invokeCommand(ctx context.Context, client AzureAksCommandClient, data *InvokeModel) error {
+	if err := client.commandPolicy.check(data.Command.ValueString()); err != nil {
+		return err
+	}
+
+	runCommand, err := runCommand(ctx, client, data.ResourceGroupName.ValueString(), data.Name.ValueString(), data.Command.ValueString(), data.Context.ValueString())
+	if err != nil {
+		return err
+	}
+
+	processRunCommand(&runCommand.RunCommandResult, &data.CommandResultModel)
+
+	return nil
+}
+
+// acquireCommand blocks until the limits of the provider allow another command on the cluster. The lock group of
+// the command is taken from the context, see contextWithLockGroup. The returned function must be called once the
+// command has finished.
+func acquireCommand(ctx context.Context, client AzureAksCommandClient, resourceGroup string, resourceName string) (func(), error) {
+	release, err := client.limiter.acquire(ctx, resourceGroup+"/"+resourceName)
+	if err != nil {
+		return nil, fmt.Errorf("waiting for a free command slot on Managed Cluster %q (Resource Group %q): %w", resourceName, resourceGroup, err)
+	}
+
+	return release, nil
+}
+
+// pendingCommandResult returns the result of a runCommand execution, which has not finished yet.
+func pendingCommandResult(commandID string) CommandResultModel {
+	return CommandResultModel{
+		Id:                 stringValueOrNull(commandID),
+		ExitCode:           types.Int64Null(),
+		Output:             types.StringNull(),
+		ProvisioningState:  types.StringValue("Running"),
+		ProvisioningReason: types.StringNull(),
+		StartedAt:          types.Int64Null(),
+		FinishedAt:         types.Int64Null(),
+	}
+}
+
+// beginRunCommand starts a runCommand execution without waiting for its result. Beside the poller, the ID of the
+// command is returned, which can be passed to ManagedClustersClient.GetCommandResult.
+func beginRunCommand(ctx context.Context, client AzureAksCommandClient, resourceGroup string, resourceName string, command string, commandContext string) (*runtime.Poller[armcontainerservice.ManagedClustersClientRunCommandResponse], string, error) {
 	payload := armcontainerservice.RunCommandRequest{
 		Command: &command,
 		Context: &commandContext,
 	}
 
-	res, err := client.managedClustersClient.Get(ctx, resourceGroup, resourceName, nil)
+	cluster, err := client.cache.getCluster(ctx, resourceGroup, resourceName, func(ctx context.Context) (armcontainerservice.ManagedCluster, error) {
+		res, err := client.managedClustersClient.Get(ctx, resourceGroup, resourceName, nil)
+		return res.ManagedCluster, err
+	})
 	if err != nil {
-		return nil, fmt.Errorf("retrieving Managed Cluster %q (Resource Group %q): %w", resourceName, resourceGroup, err)
+		return nil, "", fmt.Errorf("retrieving Managed Cluster %q (Resource Group %q): %w", resourceName, resourceGroup, err)
 	}
 
-	if *res.Properties.AADProfile.Managed {
-		token, err := client.tokenCredential.GetToken(ctx, policy.TokenRequestOptions{Scopes: []string{"6dae42f8-4368-4678-94ff-3960e28e3630"}})
-
+	if isAADManagedCluster(cluster) {
+		token, err := getClusterToken(ctx, client, auxiliaryClusterTenant(client, cluster))
 		if err != nil {
-			if !strings.Contains(err.Error(), "AADSTS1002012") {
-				return nil, err
-			}
+			return nil, "", fmt.Errorf("acquiring cluster token: %w", err)
+		}
+
+		payload.ClusterToken = &token
+	}
+
+	var rawResponse *http.Response
 
-			token, err = client.tokenCredential.GetToken(ctx, policy.TokenRequestOptions{Scopes: []string{"6dae42f8-4368-4678-94ff-3960e28e3630/.default"}})
+	poller, err := client.managedClustersClient.BeginRunCommand(policy.WithCaptureResponse(ctx, &rawResponse), resourceGroup, resourceName, payload, nil)
+	if err != nil {
+		return nil, "", err
+	}
 
-			if err != nil {
-				return nil, err
-			}
+	return poller, commandIDFromResponse(rawResponse), nil
+}
+
+// getClusterToken acquires the token for the Kubernetes API of AAD enabled clusters. Some credentials reject scopes
+// without the /.default suffix (AADSTS1002012), in which case the suffix is appended. If tenantID is empty, the token
+// is issued by the tenant of the credential. Tokens are shared through the cache of the client until they expire.
+func getClusterToken(ctx context.Context, client AzureAksCommandClient, tenantID string) (string, error) {
+	scope := client.clusterTokenScope
+	if scope == "" {
+		scope = defaultClusterTokenScope
+	}
+
+	tokenCredential := client.clusterTokenCredential
+	if tokenCredential == nil {
+		tokenCredential = client.tokenCredential
+	}
+
+	return client.cache.getToken(ctx, tenantID, scope, func(ctx context.Context) (azcore.AccessToken, error) {
+		token, err := tokenCredential.GetToken(ctx, policy.TokenRequestOptions{Scopes: []string{scope}, TenantID: tenantID})
+		if err == nil || !strings.Contains(err.Error(), "AADSTS1002012") || strings.HasSuffix(scope, "/.default") {
+			return token, err
 		}
 
-		payload.ClusterToken = &token.Token
+		return tokenCredential.GetToken(ctx, policy.TokenRequestOptions{Scopes: []string{scope + "/.default"}, TenantID: tenantID})
+	})
+}
+
+// auxiliaryClusterTenant returns the AAD tenant of the cluster, if it's one of the auxiliary tenants. Otherwise, an
+// empty string is returned.
+func auxiliaryClusterTenant(client AzureAksCommandClient, cluster armcontainerservice.ManagedCluster) string {
+	if cluster.Properties == nil || cluster.Properties.AADProfile == nil || cluster.Properties.AADProfile.TenantID == nil {
+		return ""
 	}
 
-	poller, err := client.managedClustersClient.BeginRunCommand(ctx, resourceGroup, resourceName, payload, nil)
-	if err != nil {
-		return nil, err
+	for _, tenantID := range client.auxiliaryTenantIds {
+		if strings.EqualFold(tenantID, *cluster.Properties.AADProfile.TenantID) {
+			return tenantID
+		}
 	}
 
-	runCommandPoller, err := poller.PollUntilDone(ctx, nil)
+	return ""
+}
+
+// resumeRunCommand recreates the poller of a runCommand execution from a token returned by Poller.ResumeToken.
+func resumeRunCommand(ctx context.Context, client AzureAksCommandClient, resourceGroup string, resourceName string, resumeToken string) (*runtime.Poller[armcontainerservice.ManagedClustersClientRunCommandResponse], error) {
+	return client.managedClustersClient.BeginRunCommand(ctx, resourceGroup, resourceName, armcontainerservice.RunCommandRequest{}, &armcontainerservice.ManagedClustersClientBeginRunCommandOptions{
+		ResumeToken: resumeToken,
+	})
+}
+
+// commandIDFromResponse extracts the command ID from the Location header of the runCommand response, which points
+// to .../managedClusters/{resourceName}/commandResults/{commandId}.
+func commandIDFromResponse(resp *http.Response) string {
+	if resp == nil {
+		return ""
+	}
+
+	location, err := url.Parse(resp.Header.Get("Location"))
 	if err != nil {
-		return nil, err
+		return ""
 	}
 
-	return &runCommandPoller, nil
+	segments := strings.Split(strings.Trim(location.Path, "/"), "/")
+	for i := 0; i < len(segments)-1; i++ {
+		if strings.EqualFold(segments[i], "commandResults") {
+			return segments[i+1]
+		}
+	}
+
+	return ""
 }
 
-func processRunCommand(runCommand *armcontainerservice.ManagedClustersClientRunCommandResponse, data *InvokeModel) {
+// isAADManagedCluster reports whether the AKS-managed Azure AD integration is enabled on the cluster.
+func isAADManagedCluster(cluster armcontainerservice.ManagedCluster) bool {
+	return cluster.Properties != nil &&
+		cluster.Properties.AADProfile != nil &&
+		cluster.Properties.AADProfile.Managed != nil &&
+		*cluster.Properties.AADProfile.Managed
+}
+
+func processRunCommand(runCommand *armcontainerservice.RunCommandResult, data *CommandResultModel) {
+	properties := runCommand.Properties
+	if properties == nil {
+		properties = &armcontainerservice.CommandResultProperties{}
+	}
+
 	if runCommand.ID != nil {
 		data.Id = types.StringValue(*runCommand.ID)
 	} else {
 		data.Id = types.StringNull()
 	}
 
-	if runCommand.Properties.ExitCode != nil {
-		data.ExitCode = types.Int64Value(int64(*runCommand.Properties.ExitCode))
+	if properties.ExitCode != nil {
+		data.ExitCode = types.Int64Value(int64(*properties.ExitCode))
 	} else {
 		data.ExitCode = types.Int64Null()
 	}
 
-	if runCommand.Properties.Logs != nil {
-		data.Output = types.StringValue(*runCommand.Properties.Logs)
+	if properties.Logs != nil {
+		data.Output = types.StringValue(*properties.Logs)
 	} else {
 		data.Output = types.StringNull()
 	}
 
-	if runCommand.Properties.ProvisioningState != nil {
-		data.ProvisioningState = types.StringValue(*runCommand.Properties.ProvisioningState)
+	if properties.ProvisioningState != nil {
+		data.ProvisioningState = types.StringValue(*properties.ProvisioningState)
 	} else {
 		data.ProvisioningState = types.StringNull()
 	}
 
-	if runCommand.Properties.Reason != nil {
-		data.ProvisioningReason = types.StringValue(*runCommand.Properties.Reason)
+	if properties.Reason != nil {
+		data.ProvisioningReason = types.StringValue(*properties.Reason)
 	} else {
 		data.ProvisioningReason = types.StringNull()
 	}
 
-	if runCommand.Properties.StartedAt != nil {
-		data.StartedAt = types.Int64Value(runCommand.Properties.StartedAt.Unix())
+	if properties.StartedAt != nil {
+		data.StartedAt = types.Int64Value(properties.StartedAt.Unix())
 	} else {
 		data.StartedAt = types.Int64Null()
 	}
 
-	if runCommand.Properties.FinishedAt != nil {
-		data.FinishedAt = types.Int64Value(runCommand.Properties.FinishedAt.Unix())
+	if properties.FinishedAt != nil {
+		data.FinishedAt = types.Int64Value(properties.FinishedAt.Unix())
 	} else {
 		data.FinishedAt = types.Int64Null()
 	}
 }
+
+// shellQuote quotes a value, so it can be passed safely as single argument to a shell command.
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'"'"'`) + "'"
+}
+
+// runKubectl executes a command through runCommand and returns its output. A non-zero exit code is returned as error.
+func runKubectl(ctx context.Context, client AzureAksCommandClient, resourceGroup string, resourceName string, command string, commandContext string) (string, error) {
+	res, err := runCommand(ctx, client, resourceGroup, resourceName, command, commandContext)
+	if err != nil {
+		return "", err
+	}
+
+	var logs string
+	if res.Properties != nil && res.Properties.Logs != nil {
+		logs = *res.Properties.Logs
+	}
+
+	if res.Properties == nil || res.Properties.ExitCode == nil || *res.Properties.ExitCode != 0 {
+		var exitCode string
+		if res.Properties != nil && res.Properties.ExitCode != nil {
+			exitCode = fmt.Sprintf("%d", *res.Properties.ExitCode)
+		}
+
+		return logs, fmt.Errorf("command %q exited with exit code %q: %s", command, exitCode, logs)
+	}
+
+	return logs, nil
+}
+
+// buildCommandContext returns a base64 encoded zip file containing the files, which can be passed as context to runCommand.
+func buildCommandContext(files map[string][]byte) (string, error) {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+
+	w := zip.NewWriter(&buf)
+
+	for _, name := range names {
+		f, err := w.Create(name)
+		if err != nil {
+			return "", err
+		}
+
+		if _, err = f.Write(files[name]); err != nil {
+			return "", err
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// sleepContext pauses the current goroutine for at least the duration d or until the context is canceled.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
+
+// stringValueOrDefault returns the value of an optional attribute or defaultValue, if it's not set.
+func stringValueOrDefault(value types.String, defaultValue string) string {
+	if value.IsNull() || value.IsUnknown() {
+		return defaultValue
+	}
+
+	return value.ValueString()
+}
+
+// stringListValues returns the known elements of an optional list attribute. A null or unknown list has no elements.
+func stringListValues(value types.List) []string {
+	if value.IsNull() || value.IsUnknown() {
+		return nil
+	}
+
+	values := make([]string, 0, len(value.Elements()))
+
+	for _, element := range value.Elements() {
+		if v, ok := element.(types.String); ok && !v.IsNull() && !v.IsUnknown() {
+			values = append(values, v.ValueString())
+		}
+	}
+
+	return values
+}
+
+// stringPointerValue returns the value of the pointer or an empty string, if it's nil.
+func stringPointerValue(value *string) string {
+	if value == nil {
+		return ""
+	}
+
+	return *value
+}
+
+// stringValueOrNull returns a null value for empty strings.
+func stringValueOrNull(value string) types.String {
+	if value == "" {
+		return types.StringNull()
+	}
+
+	return types.StringValue(value)
+}
@@ -0,0 +1,121 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	azfake "github.com/Azure/azure-sdk-for-go/sdk/azcore/fake"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+func newFakeClient(t *testing.T, backend *FakeManagedClusters) AzureAksCommandClient {
+	t.Helper()
+
+	managedClustersClient, err := backend.NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return AzureAksCommandClient{
+		tokenCredential:       &azfake.TokenCredential{},
+		limiter:               newCommandLimiter(0, 0),
+		cache:                 newClusterCache(),
+		managedClustersClient: managedClustersClient,
+	}
+}
+
+// newFakeBackend returns a backend with a cluster with and without Azure AD integration. The commands "exit 3",
+// "sleep", "broken" and "busy" fail or take several polls, all other commands print "hello".
+func newFakeBackend() *FakeManagedClusters {
+	return &FakeManagedClusters{
+		Clusters: map[string]FakeCluster{
+			"rg/plain": {},
+			"rg/aad":   {AAD: true, TenantID: "11111111-1111-1111-1111-111111111111"},
+		},
+		Handler: func(request FakeRunCommandRequest) FakeCommandResult {
+			switch *request.Request.Command {
+			case "exit 3":
+				return FakeCommandResult{ExitCode: 3, Logs: "failed\n"}
+			case "sleep":
+				return FakeCommandResult{Polls: 3, Logs: "done\n"}
+			case "broken":
+				return FakeCommandResult{ProvisioningState: "Failed", Reason: "pod failed to start"}
+			case "busy":
+				return FakeCommandResult{StatusCode: 409, ErrorCode: "Conflict"}
+			}
+
+			return FakeCommandResult{Logs: "hello\n"}
+		},
+	}
+}
+
+func newFakeInvokeModel(resourceGroup string, name string, command string, wait bool) *InvokeModel {
+	return &InvokeModel{
+		ResourceGroupName: types.StringValue(resourceGroup),
+		Name:              types.StringValue(name),
+		Command:           types.StringValue(command),
+		Context:           types.StringNull(),
+		Wait:              types.BoolValue(wait),
+	}
+}
+
+func TestInvokeCommand(t *testing.T) {
+	backend := newFakeBackend()
+
+	client := newFakeClient(t, backend)
+
+	tests := []struct {
+		name              string
+		cluster           string
+		command           string
+		exitCode          int64
+		output            string
+		provisioningState string
+		wantErr           bool
+	}{
+		{name: "non-AAD cluster", cluster: "plain", command: "echo hello", output: "hello\n", provisioningState: "Succeeded"},
+		{name: "AAD cluster", cluster: "aad", command: "echo hello", output: "hello\n", provisioningState: "Succeeded"},
+		{name: "exit code", cluster: "plain", command: "exit 3", exitCode: 3, output: "failed\n", provisioningState: "Succeeded"},
+		{name: "long poll", cluster: "plain", command: "sleep", output: "done\n", provisioningState: "Succeeded"},
+		{name: "failed command", cluster: "plain", command: "broken", wantErr: true},
+		{name: "rejected command", cluster: "plain", command: "busy", wantErr: true},
+		{name: "unknown cluster", cluster: "missing", command: "echo hello", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := newFakeInvokeModel("rg", tt.cluster, tt.command, true)
+
+			err := invokeCommand(context.Background(), client, data)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if data.ExitCode.ValueInt64() != tt.exitCode {
+				t.Errorf("exit_code = %d, want %d", data.ExitCode.ValueInt64(), tt.exitCode)
+			}
+
+			if data.Output.ValueString() != tt.output {
+				t.Errorf("output = %q, want %q", data.Output.ValueString(), tt.output)
+			}
+
+			if data.ProvisioningState.ValueString() != tt.provisioningState {
+				t.Errorf("provisioning_state = %q, want %q", data.ProvisioningState.ValueString(), tt.provisioningState)
+			}
+		})
+	}
+
+	for _, request := range backend.Requests() {
+		if hasToken := request.Request.ClusterToken != nil; hasToken != (request.ResourceName == "aad") {
+			t.Errorf("cluster token sent to %q: %t", request.ResourceName, hasToken)
+		}
+	}
+}
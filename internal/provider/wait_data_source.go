@@ -0,0 +1,337 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/containerservice/armcontainerservice/v9"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+const (
+	// waitAttemptTimeout is the maximum time a single kubectl wait may block inside one runCommand execution.
+	waitAttemptTimeout = 60 * time.Second
+
+	waitDefaultTimeout       = "10m"
+	waitDefaultRetryInterval = "10s"
+)
+
+// Ensure provider defined types fully satisfy framework interfaces.
+var _ datasource.DataSource = &WaitDataSource{}
+var _ datasource.DataSourceWithValidateConfig = &WaitDataSource{}
+
+func NewWaitDataSource() datasource.DataSource {
+	return &WaitDataSource{}
+}
+
+// WaitDataSource defines the data source implementation.
+type WaitDataSource struct {
+	data AzureAksCommandClient
+}
+
+// WaitModel describes the data source data model.
+type WaitModel struct {
+	Id                types.String `tfsdk:"id"`
+	Name              types.String `tfsdk:"name"`
+	ResourceGroupName types.String `tfsdk:"resource_group_name"`
+	Resource          types.String `tfsdk:"resource"`
+	Namespace         types.String `tfsdk:"namespace"`
+	For               types.String `tfsdk:"for"`
+	RolloutStatus     types.Bool   `tfsdk:"rollout_status"`
+	Timeout           types.String `tfsdk:"timeout"`
+	RetryInterval     types.String `tfsdk:"retry_interval"`
+	Attempts          types.Int64  `tfsdk:"attempts"`
+	ExitCode          types.Int64  `tfsdk:"exit_code"`
+	Output            types.String `tfsdk:"output"`
+	Status            types.String `tfsdk:"status"`
+}
+
+func (d *WaitDataSource) Metadata(_ context.Context, req datasource.MetadataRequest, resp *datasource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_wait"
+}
+
+func (d *WaitDataSource) Schema(_ context.Context, _ datasource.SchemaRequest, resp *datasource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		MarkdownDescription: "A data source to wait until a Kubernetes resource inside AKS reaches a condition, e.g. a deployment is rolled out, a CRD is established or a job is complete. " +
+			"The check is executed through `kubectl wait` or `kubectl rollout status` and retried until `timeout` is reached, even if it exceeds the timeout of a single runCommand execution. Failed runCommand executions, e.g. if the pod of the command couldn't be started, are retried as well.",
+		Attributes: map[string]schema.Attribute{
+			"name": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "The name of the Managed Kubernetes Cluster.",
+			},
+			"resource_group_name": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "Specifies the Resource Group where the Managed Kubernetes Cluster exists.",
+			},
+			"resource": schema.StringAttribute{
+				Required:            true,
+				MarkdownDescription: "The Kubernetes resource to wait for, e.g. `deployment/my-app`, `crd/certificates.cert-manager.io` or `job/migrate`.",
+			},
+			"namespace": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "The namespace of the Kubernetes resource. Omit for cluster scoped resources.",
+			},
+			"for": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "The condition passed to `kubectl wait --for`, e.g. `condition=Available`, `condition=Established`, `condition=Complete` or `jsonpath={.status.phase}=Running`. Conflicts with `rollout_status`.",
+			},
+			"rollout_status": schema.BoolAttribute{
+				Optional:            true,
+				MarkdownDescription: "Wait through `kubectl rollout status` instead of `kubectl wait`. Conflicts with `for`.",
+			},
+			"timeout": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "The maximum duration to wait for the condition, e.g. `30s` or `20m`. Defaults to `" + waitDefaultTimeout + "`.",
+			},
+			"retry_interval": schema.StringAttribute{
+				Optional:            true,
+				MarkdownDescription: "The duration to wait between two attempts. Defaults to `" + waitDefaultRetryInterval + "`.",
+			},
+			"id": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "The runCommand id of the last attempt",
+			},
+			"attempts": schema.Int64Attribute{
+				Computed:            true,
+				MarkdownDescription: "The number of runCommand executions needed until the condition was met.",
+			},
+			"exit_code": schema.Int64Attribute{
+				Computed:            true,
+				MarkdownDescription: "The exit code of the last attempt",
+			},
+			"output": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "The output of the last attempt",
+			},
+			"status": schema.StringAttribute{
+				Computed:            true,
+				MarkdownDescription: "The final observed `.status` of the Kubernetes resource as JSON.",
+			},
+		},
+	}
+}
+
+func (d *WaitDataSource) ValidateConfig(ctx context.Context, req datasource.ValidateConfigRequest, resp *datasource.ValidateConfigResponse) {
+	var data WaitModel
+
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	rolloutStatus := !data.RolloutStatus.IsNull() && data.RolloutStatus.ValueBool()
+
+	if !data.For.IsUnknown() && !data.RolloutStatus.IsUnknown() {
+		if data.For.IsNull() && !rolloutStatus {
+			resp.Diagnostics.AddAttributeError(path.Root("for"), "Missing wait condition", "One of for or rollout_status must be configured.")
+		} else if !data.For.IsNull() && rolloutStatus {
+			resp.Diagnostics.AddAttributeError(path.Root("for"), "Conflicting wait condition", "Only one of for or rollout_status can be configured.")
+		}
+	}
+
+	for _, attribute := range []struct {
+		name  string
+		value types.String
+	}{
+		{"timeout", data.Timeout},
+		{"retry_interval", data.RetryInterval},
+	} {
+		if attribute.value.IsNull() || attribute.value.IsUnknown() {
+			continue
+		}
+
+		if _, err := time.ParseDuration(attribute.value.ValueString()); err != nil {
+			resp.Diagnostics.AddAttributeError(path.Root(attribute.name), "Invalid duration", err.Error())
+		}
+	}
+}
+
+func (d *WaitDataSource) Configure(_ context.Context, req datasource.ConfigureRequest, resp *datasource.ConfigureResponse) {
+	// Prevent panic if the provider has not been configured.
+	if req.ProviderData == nil {
+		return
+	}
+
+	data, ok := req.ProviderData.(AzureAksCommandClient)
+
+	if !ok {
+		resp.Diagnostics.AddError(
+			"Unexpected Resource Configure Type",
+			fmt.Sprintf("Expected AzureAksCommandClient, got: %T. Please report this issue to the provider developers.", req.ProviderData),
+		)
+
+		return
+	}
+
+	d.data = data
+}
+
+func (d *WaitDataSource) Read(ctx context.Context, req datasource.ReadRequest, resp *datasource.ReadResponse) {
+	var data *WaitModel
+
+	// Read Terraform configuration data into the model
+	resp.Diagnostics.Append(req.Config.Get(ctx, &data)...)
+
+	// Prevent panic if the provider has not been configured.
+	if d.data.managedClustersClient == nil || d.data.tokenCredential == nil {
+		resp.Diagnostics.AddError(
+			"Unconfigured Client",
+			"Expected configured client. Please report this issue to the provider developers.",
+		)
+	}
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	timeout, _ := time.ParseDuration(stringValueOrDefault(data.Timeout, waitDefaultTimeout))
+	retryInterval, _ := time.ParseDuration(stringValueOrDefault(data.RetryInterval, waitDefaultRetryInterval))
+	deadline := time.Now().Add(timeout)
+
+	resourceGroupName := data.ResourceGroupName.ValueString()
+	name := data.Name.ValueString()
+	statusCommand := buildStatusCommand(data)
+	ready := false
+
+	addCommandPolicyError(&resp.Diagnostics, path.Root("resource"), d.data, statusCommand)
+
+	if resp.Diagnostics.HasError() {
+		return
+	}
+
+	for attempt := int64(1); ; attempt++ {
+		waitCommand := buildWaitCommand(data, min(time.Until(deadline), waitAttemptTimeout))
+
+		addCommandPolicyError(&resp.Diagnostics, path.Root("resource"), d.data, waitCommand)
+
+		if resp.Diagnostics.HasError() {
+			return
+		}
+
+		result, err := runWaitAttempt(ctx, d.data, resourceGroupName, name, waitCommand)
+		if err != nil {
+			addAzureError(&resp.Diagnostics, "Error while executing runCommand", err)
+			return
+		}
+
+		if result.ID != nil {
+			data.Id = types.StringValue(*result.ID)
+		}
+
+		data.Attempts = types.Int64Value(attempt)
+		data.ExitCode = types.Int64Null()
+		data.Output = types.StringNull()
+
+		if result.Properties != nil {
+			if result.Properties.ExitCode != nil {
+				data.ExitCode = types.Int64Value(int64(*result.Properties.ExitCode))
+			}
+
+			if result.Properties.Logs != nil {
+				data.Output = types.StringValue(*result.Properties.Logs)
+			}
+		}
+
+		if !data.ExitCode.IsNull() && data.ExitCode.ValueInt64() == 0 {
+			ready = true
+			break
+		}
+
+		if time.Now().Add(retryInterval).After(deadline) {
+			break
+		}
+
+		if err = sleepContext(ctx, retryInterval); err != nil {
+			addAzureError(&resp.Diagnostics, "Error while waiting for Kubernetes resource", err)
+			return
+		}
+	}
+
+	result, err := runWaitAttempt(ctx, d.data, resourceGroupName, name, statusCommand)
+	if err != nil {
+		addAzureError(&resp.Diagnostics, "Error while executing runCommand", err)
+		return
+	}
+
+	data.Status = types.StringNull()
+
+	if result.Properties != nil && result.Properties.Logs != nil && !strings.EqualFold(stringPointerValue(result.Properties.ProvisioningState), "Failed") {
+		data.Status = types.StringValue(strings.TrimSpace(*result.Properties.Logs))
+	}
+
+	if !ready {
+		resp.Diagnostics.AddError(
+			"Timeout while waiting for Kubernetes resource",
+			fmt.Sprintf("%s did not reach the expected condition within %s.\n\nLast output:\n%s\n\nLast observed status:\n%s",
+				data.Resource.ValueString(), timeout, data.Output.ValueString(), data.Status.ValueString()),
+		)
+
+		return
+	}
+
+	// Save data into Terraform state
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// runWaitAttempt executes a command of the wait data source. A Failed command, e.g. whose pod couldn't be started, is
+// no error. Its result is returned without exit code instead, so the condition is checked again until the timeout is
+// reached. Without logs, the reason of the failure is returned as logs.
+func runWaitAttempt(ctx context.Context, client AzureAksCommandClient, resourceGroupName string, name string, command string) (armcontainerservice.RunCommandResult, error) {
+	res, err := runCommand(ctx, client, resourceGroupName, name, command, "")
+	if err == nil {
+		return res.RunCommandResult, nil
+	}
+
+	result, ok := failedRunCommandResult(err)
+	if !ok {
+		return result, err
+	}
+
+	tflog.Warn(ctx, "runCommand failed, the condition is checked again", map[string]interface{}{
+		"command": command,
+		"reason":  stringPointerValue(result.Properties.Reason),
+	})
+
+	// The exit code of a Failed command doesn't tell whether the condition was met.
+	result.Properties.ExitCode = nil
+
+	if result.Properties.Logs == nil || *result.Properties.Logs == "" {
+		result.Properties.Logs = to.Ptr("runCommand failed: " + stringPointerValue(result.Properties.Reason))
+	}
+
+	return result, nil
+}
+
+// buildWaitCommand returns the kubectl command which blocks at most timeout until the condition is met.
+func buildWaitCommand(data *WaitModel, timeout time.Duration) string {
+	kubectlTimeout := fmt.Sprintf("--timeout=%ds", max(int64(timeout.Seconds()), 1))
+
+	if !data.RolloutStatus.IsNull() && data.RolloutStatus.ValueBool() {
+		return strings.Join(append([]string{"kubectl", "rollout", "status", kubectlTimeout}, kubectlResourceArgs(data)...), " ")
+	}
+
+	return strings.Join(append([]string{"kubectl", "wait", "--for=" + shellQuote(data.For.ValueString()), kubectlTimeout}, kubectlResourceArgs(data)...), " ")
+}
+
+// buildStatusCommand returns the kubectl command which prints the status of the resource.
+func buildStatusCommand(data *WaitModel) string {
+	return strings.Join(append([]string{"kubectl", "get", "-o", shellQuote("jsonpath={.status}")}, kubectlResourceArgs(data)...), " ")
+}
+
+func kubectlResourceArgs(data *WaitModel) []string {
+	var args []string
+
+	if !data.Namespace.IsNull() && data.Namespace.ValueString() != "" {
+		args = append(args, "--namespace", shellQuote(data.Namespace.ValueString()))
+	}
+
+	return append(args, shellQuote(data.Resource.ValueString()))
+}
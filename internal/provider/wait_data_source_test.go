@@ -0,0 +1,84 @@
+package provider
+
+import (
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-testing/helper/resource"
+)
+
+// newFakeWaitBackend returns a backend, whose first failedAttempts kubectl wait commands reach the provisioning state
+// Failed. Later attempts meet the condition.
+func newFakeWaitBackend(failedAttempts int64) *FakeManagedClusters {
+	var attempts atomic.Int64
+
+	return &FakeManagedClusters{
+		Clusters: map[string]FakeCluster{"rg/plain": {}},
+		Handler: func(request FakeRunCommandRequest) FakeCommandResult {
+			command := *request.Request.Command
+
+			switch {
+			case strings.HasPrefix(command, "kubectl wait"):
+				if attempts.Add(1) <= failedAttempts {
+					return FakeCommandResult{ProvisioningState: "Failed", Reason: "pod failed to start"}
+				}
+
+				return FakeCommandResult{Logs: "deployment.apps/app condition met\n"}
+			case strings.HasPrefix(command, "kubectl get"):
+				return FakeCommandResult{Logs: `{"availableReplicas":1}`}
+			}
+
+			return FakeCommandResult{ExitCode: 1, Logs: "unexpected command: " + command}
+		},
+	}
+}
+
+func TestAccWaitDataSourceRetriesFailedAttempts(t *testing.T) {
+	const config = `
+data "azureakscommand_wait" "test" {
+  resource_group_name = "rg"
+  name                = "plain"
+  resource            = "deployment/app"
+  for                 = "condition=Available"
+  timeout             = "10s"
+  retry_interval      = "1ms"
+}
+`
+
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactoriesWithFake(t, newFakeWaitBackend(2)),
+		Steps: []resource.TestStep{
+			{
+				Config: config,
+				Check: resource.ComposeAggregateTestCheckFunc(
+					resource.TestCheckResourceAttr("data.azureakscommand_wait.test", "attempts", "3"),
+					resource.TestCheckResourceAttr("data.azureakscommand_wait.test", "exit_code", "0"),
+					resource.TestCheckResourceAttr("data.azureakscommand_wait.test", "status", `{"availableReplicas":1}`),
+				),
+			},
+		},
+	})
+}
+
+func TestAccWaitDataSourceTimeout(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		ProtoV6ProviderFactories: testAccProtoV6ProviderFactoriesWithFake(t, newFakeWaitBackend(1<<62)),
+		Steps: []resource.TestStep{
+			{
+				Config: `
+data "azureakscommand_wait" "test" {
+  resource_group_name = "rg"
+  name                = "plain"
+  resource            = "deployment/app"
+  for                 = "condition=Available"
+  timeout             = "1s"
+  retry_interval      = "100ms"
+}
+`,
+				ExpectError: regexp.MustCompile(`(?s)Timeout while waiting for Kubernetes resource.*pod failed to start`),
+			},
+		},
+	})
+}